@@ -1,11 +1,16 @@
 from __future__ import annotations
 
 import argparse
-from dataclasses import dataclass
-from typing import Tuple
+import json
+from dataclasses import asdict, dataclass, field
+from typing import List, Tuple
 
 from rich import print as rprint
 
+from demod import DIODE_MODELS
+from signals import AM_VARIANTS, INTEGRATION_METHODS
+from noise import NOISE_DISTRIBUTIONS
+
 
 @dataclass
 class SimulationParams:
@@ -13,14 +18,47 @@ class SimulationParams:
     duration: float = 0.1  # seconds
     message_freq: float = 1_000.0  # Hz
     carrier_freq: float = 10_000.0  # Hz
-    am_index: float = 0.5  # 0..1 typical
+    am_index: float = 0.5  # 0..1 typical, above 1 overmodulates
+    am_variant: str = "full-carrier"  # full-carrier | suppressed-carrier | reduced-carrier
+    am_pilot_level: float = 0.1  # reduced-carrier only: carrier term relative to full carrier, 0..1
     fm_deviation: float = 5_000.0  # Hz per unit amplitude of m(t)
+    fm_integration: str = "rectangular"  # message phase integral: rectangular | trapezoidal | simpson
     snr_min: float = 0.0  # dB
     snr_max: float = 30.0  # dB
     snr_step: float = 5.0  # dB
+    snr_values: List[float] = field(default_factory=list)  # explicit SNR levels (dB), override min/max/step
     trials: int = 100
+    convergence_tolerance: float = 0.0  # dB; stop an SNR point once the AM and FM standard errors fall below it (0 = off)
+    max_trials: int = 0  # per-SNR cap when converging, run in batches of `trials` (0 -> 10 * trials)
+    seed: int = 0  # base seed; each (SNR, trial) noise seed is derived from it
+    workers: int = 1  # Monte Carlo worker processes
     message_amplitude: float = 1.0
     carrier_amplitude: float = 1.0
+    am_demodulator: str = "envelope"  # envelope | hilbert | coherent
+    am_diode: str = "full-wave"  # envelope detector rectifier: full-wave | half-wave | exponential
+    fm_demodulator: str = "instantaneous"  # instantaneous | pll
+    pll_loop_bandwidth: float = 0.0  # Hz, 0 -> derived from fm_deviation
+    fm_emphasis_tau: float = 0.0  # s, pre/de-emphasis time constant (0 = off, 75e-6 typical)
+    include_dsbsc: bool = False  # also simulate DSB-SC with a Costas loop receiver
+    include_pm: bool = False  # also simulate phase modulation
+    pm_index: float = 1.0  # rad per unit amplitude of m(t)
+    noise_distribution: str = "gaussian"  # gaussian | uniform | laplacian
+    impulse_probability: float = 0.0  # per-sample click probability added after AWGN (0 = off)
+    impulse_amplitude: float = 5.0  # click magnitude
+    demod_cutoff_hz: float = 0.0  # post-detection low-pass (Hz), 0 -> 2 * message_freq
+    fading_doppler_hz: float = 0.0  # Rayleigh fading Doppler spread applied before AWGN (0 = off)
+    extra_modulations: List[str] = field(default_factory=list)  # registered schemes simulated alongside AM/FM
+    save_detailed: bool = False  # keep every trial's output SNR per scheme in the results
+    outlier_policy: str = "none"  # none | mad | percentile, applied to each SNR point's trials
+    outlier_threshold: float = 0.0  # mad: modified z-score limit; percentile: % cut per tail (0 -> 3.5 / 1.0)
+    clamp_overmodulation: bool = False  # clip a negative AM envelope to zero instead of reversing the carrier
+    channel_filter: bool = False  # band-pass the received AM/FM signal around the carrier before demodulating
+    fm_limiter: bool = False  # hard-limit and band-pass the received FM signal before the discriminator
+
+
+AM_DEMODULATORS = ("envelope", "hilbert", "coherent")
+FM_DEMODULATORS = ("instantaneous", "pll")
+OUTLIER_POLICIES = ("none", "mad", "percentile")
 
 
 # ----------------------- Validation helpers -----------------------
@@ -45,6 +83,84 @@ def _positive(value: float, default: float) -> float:
     return v
 
 
+def is_overmodulated(p: SimulationParams) -> bool:
+    """True when the AM envelope 1 + ka*m(t) goes negative for the tone message, i.e. ka*Am > 1."""
+    return p.am_index * abs(p.message_amplitude) > 1.0
+
+
+def am_receiver(p: SimulationParams) -> str:
+    """
+    AM demodulator actually used: p.am_demodulator for a full carrier,
+    otherwise "coherent" (a Costas loop when the carrier is suppressed),
+    since envelope and Hilbert detection need the carrier term.
+    """
+    return p.am_demodulator if p.am_variant == "full-carrier" else "coherent"
+
+
+def save_params_json(p: SimulationParams, filename: str) -> None:
+    """Write parameters to a JSON file readable by load_params_json."""
+    with open(filename, "w") as f:
+        json.dump(asdict(p), f, indent=2)
+
+
+def load_params_json(filename: str) -> SimulationParams:
+    """
+    Load parameters from a JSON object of SimulationParams fields.
+    
+    Missing fields keep their defaults. The result is checked with
+    check_params rather than silently repaired.
+    
+    Raises:
+        FileNotFoundError: if the file does not exist
+        ValueError: on malformed JSON, unknown fields or invalid values
+    """
+    with open(filename) as f:
+        try:
+            data = json.load(f)
+        except json.JSONDecodeError as exc:
+            raise ValueError(f"{filename}: invalid JSON: {exc}") from None
+    if not isinstance(data, dict):
+        raise ValueError(f"{filename}: expected a JSON object of parameters")
+    unknown = sorted(set(data) - set(SimulationParams.__dataclass_fields__))
+    if unknown:
+        raise ValueError(f"{filename}: unknown parameter(s): {', '.join(unknown)}")
+    p = SimulationParams(**data)
+    check_params(p)
+    return p
+
+
+def parse_snr_range(text: str) -> List[float]:
+    """
+    Parse a comma-separated list of SNR levels in dB, e.g. "0, 5, 12.5".
+    
+    Returns:
+        Sorted, de-duplicated levels
+    
+    Raises:
+        ValueError: on empty input or a value that is not a number
+    """
+    tokens = [token.strip() for token in text.split(",")]
+    if not any(tokens):
+        raise ValueError("SNR range is empty")
+    values = []
+    for token in tokens:
+        try:
+            value = float(token)
+        except ValueError:
+            raise ValueError(f"Malformed SNR value {token!r} in {text!r}") from None
+        if value != value or value in (float("inf"), float("-inf")):
+            raise ValueError(f"SNR value must be finite, got {token!r}")
+        values.append(value)
+    return sorted(set(values))
+
+
+def _snr_range_arg(text: str) -> List[float]:
+    try:
+        return parse_snr_range(text)
+    except ValueError as exc:
+        raise argparse.ArgumentTypeError(str(exc))
+
+
 def _positive_int(value: int, default: int) -> int:
     try:
         v = int(value)
@@ -60,16 +176,54 @@ def validate_params(p: SimulationParams) -> SimulationParams:
     p.duration = _positive(p.duration, 0.1)
     p.message_freq = _positive(p.message_freq, 1_000.0)
     p.carrier_freq = _positive(p.carrier_freq, 10_000.0)
-    p.am_index = _clamp(p.am_index, 0.0, 1.0, 0.5)
+    # ka > 1 is kept: overmodulation is flagged in the summary and handled by clamp_overmodulation
+    p.am_index = _clamp(p.am_index, 0.0, float("inf"), 0.5)
+    if p.am_variant not in AM_VARIANTS:
+        p.am_variant = "full-carrier"
+    if not 0.0 < p.am_pilot_level < 1.0:
+        p.am_pilot_level = 0.1
     p.fm_deviation = _positive(p.fm_deviation, 5_000.0)
+    if p.fm_integration not in INTEGRATION_METHODS:
+        p.fm_integration = "rectangular"
     # SNR range
     if p.snr_step <= 0:
         p.snr_step = 5.0
     if p.snr_min > p.snr_max:
         p.snr_min, p.snr_max = p.snr_max, p.snr_min
     p.trials = _positive_int(p.trials, 100)
+    p.workers = _positive_int(p.workers, 1)
+    if p.convergence_tolerance < 0:
+        p.convergence_tolerance = 0.0
+    if p.max_trials != 0 and p.max_trials < p.trials:
+        p.max_trials = 0
+    if p.seed < 0:
+        p.seed = 0
     p.message_amplitude = _positive(p.message_amplitude, 1.0)
     p.carrier_amplitude = _positive(p.carrier_amplitude, 1.0)
+    if p.am_demodulator not in AM_DEMODULATORS:
+        p.am_demodulator = "envelope"
+    if p.am_diode not in DIODE_MODELS:
+        p.am_diode = "full-wave"
+    if p.fm_demodulator not in FM_DEMODULATORS:
+        p.fm_demodulator = "instantaneous"
+    if p.pll_loop_bandwidth < 0:
+        p.pll_loop_bandwidth = 0.0
+    if p.noise_distribution not in NOISE_DISTRIBUTIONS:
+        p.noise_distribution = "gaussian"
+    if p.outlier_policy not in OUTLIER_POLICIES:
+        p.outlier_policy = "none"
+    if p.outlier_threshold < 0 or (p.outlier_policy == "percentile" and p.outlier_threshold >= 50.0):
+        p.outlier_threshold = 0.0
+    p.pm_index = _positive(p.pm_index, 1.0)
+    if p.fm_emphasis_tau < 0:
+        p.fm_emphasis_tau = 0.0
+    p.impulse_probability = _clamp(p.impulse_probability, 0.0, 1.0, 0.0)
+    if p.impulse_amplitude < 0:
+        p.impulse_amplitude = 5.0
+    if p.fading_doppler_hz < 0:
+        p.fading_doppler_hz = 0.0
+    if p.demod_cutoff_hz < 0:
+        p.demod_cutoff_hz = 0.0
     # Additional sanity: Nyquist - keep carrier and message below fs/2
     nyquist = p.sampling_rate / 2.0
     if p.carrier_freq >= nyquist:
@@ -79,22 +233,108 @@ def validate_params(p: SimulationParams) -> SimulationParams:
     return p
 
 
+def check_params(p: SimulationParams) -> None:
+    """
+    Strict counterpart to validate_params: raise instead of repairing.
+
+    Raises:
+        ValueError: listing every parameter that would make the simulation meaningless
+    """
+    errors = []
+    for name in ("sampling_rate", "duration", "message_freq", "carrier_freq", "fm_deviation",
+                 "message_amplitude", "carrier_amplitude"):
+        value = getattr(p, name)
+        if not value > 0:
+            errors.append(f"{name} must be positive, got {value}")
+    if p.trials < 1:
+        errors.append(f"trials must be at least 1, got {p.trials}")
+    if p.workers < 1:
+        errors.append(f"workers must be at least 1, got {p.workers}")
+    if p.convergence_tolerance < 0:
+        errors.append(f"convergence_tolerance must be non-negative, got {p.convergence_tolerance}")
+    if p.max_trials != 0 and p.max_trials < p.trials:
+        errors.append(f"max_trials must be 0 or at least trials ({p.trials}), got {p.max_trials}")
+    if p.seed < 0:
+        errors.append(f"seed must be non-negative, got {p.seed}")
+    if p.snr_step <= 0 and not p.snr_values:
+        errors.append(f"snr_step must be positive, got {p.snr_step}")
+    if p.fm_integration not in INTEGRATION_METHODS:
+        errors.append(f"fm_integration must be one of {', '.join(INTEGRATION_METHODS)}, got {p.fm_integration!r}")
+    if p.am_variant not in AM_VARIANTS:
+        errors.append(f"am_variant must be one of {', '.join(AM_VARIANTS)}, got {p.am_variant!r}")
+    if not 0.0 < p.am_pilot_level < 1.0:
+        errors.append(f"am_pilot_level must be in (0, 1), got {p.am_pilot_level}")
+    if p.am_diode not in DIODE_MODELS:
+        errors.append(f"am_diode must be one of {', '.join(DIODE_MODELS)}, got {p.am_diode!r}")
+    if p.noise_distribution not in NOISE_DISTRIBUTIONS:
+        errors.append(f"noise_distribution must be one of {', '.join(NOISE_DISTRIBUTIONS)}, got {p.noise_distribution!r}")
+    if p.outlier_policy not in OUTLIER_POLICIES:
+        errors.append(f"outlier_policy must be one of {', '.join(OUTLIER_POLICIES)}, got {p.outlier_policy!r}")
+    if p.outlier_threshold < 0 or (p.outlier_policy == "percentile" and p.outlier_threshold >= 50.0):
+        errors.append(f"outlier_threshold must be non-negative (below 50 for percentile), got {p.outlier_threshold}")
+    if not (0.0 <= p.impulse_probability <= 1.0):
+        errors.append(f"impulse_probability must be in [0, 1], got {p.impulse_probability}")
+    if p.fading_doppler_hz < 0:
+        errors.append(f"fading_doppler_hz must be non-negative, got {p.fading_doppler_hz}")
+    if p.extra_modulations:
+        from modulation import registered_modulations
+        for name in p.extra_modulations:
+            if name in ("am", "fm") or name not in registered_modulations():
+                errors.append(f"extra_modulations entry {name!r} is not a registered scheme other than am/fm")
+    if p.sampling_rate > 0:
+        nyquist = p.sampling_rate / 2.0
+        if p.carrier_freq >= nyquist:
+            errors.append(f"carrier_freq {p.carrier_freq} Hz is at or above Nyquist ({nyquist} Hz)")
+        if p.message_freq >= nyquist:
+            errors.append(f"message_freq {p.message_freq} Hz is at or above Nyquist ({nyquist} Hz)")
+    if errors:
+        raise ValueError("Invalid simulation parameters: " + "; ".join(errors))
+
+
 # ----------------------- Argument parsing -----------------------
 
 def build_arg_parser() -> argparse.ArgumentParser:
     parser = argparse.ArgumentParser(description="AM/FM Monte Carlo Simulation Parameters")
+    parser.add_argument("--config", dest="config_file", type=str, help="JSON parameter file (other flags override it)")
     parser.add_argument("--fs", "--sampling-rate", dest="sampling_rate", type=float, help="Sampling rate (Hz)")
     parser.add_argument("--duration", type=float, help="Signal duration (s)")
     parser.add_argument("--fm", "--message-freq", dest="message_freq", type=float, help="Message frequency (Hz)")
     parser.add_argument("--fc", "--carrier-freq", dest="carrier_freq", type=float, help="Carrier frequency (Hz)")
-    parser.add_argument("--ka", "--am-index", dest="am_index", type=float, help="AM modulation index (0..1)")
+    parser.add_argument("--ka", "--am-index", dest="am_index", type=float, help="AM modulation index (0..1; above 1 overmodulates)")
     parser.add_argument("--fd", "--fm-deviation", dest="fm_deviation", type=float, help="FM frequency deviation (Hz)")
     parser.add_argument("--snr-min", dest="snr_min", type=float, help="Minimum SNR (dB)")
     parser.add_argument("--snr-max", dest="snr_max", type=float, help="Maximum SNR (dB)")
     parser.add_argument("--snr-step", dest="snr_step", type=float, help="SNR step (dB)")
+    parser.add_argument("--snr-range", dest="snr_values", type=_snr_range_arg, help="Comma-separated SNR levels (dB), overrides --snr-min/--snr-max/--snr-step")
     parser.add_argument("--trials", dest="trials", type=int, help="Number of Monte Carlo trials")
+    parser.add_argument("--converge", dest="convergence_tolerance", type=float, help="Stop each SNR point once the mean's standard error is below this (dB), 0 = fixed trials")
+    parser.add_argument("--max-trials", dest="max_trials", type=int, help="Trial cap per SNR point with --converge, 0 = 10 x trials")
+    parser.add_argument("--seed", dest="seed", type=int, help="Base random seed for Monte Carlo noise")
+    parser.add_argument("--workers", dest="workers", type=int, help="Monte Carlo worker processes")
     parser.add_argument("--Am", dest="message_amplitude", type=float, help="Message amplitude")
     parser.add_argument("--Ac", dest="carrier_amplitude", type=float, help="Carrier amplitude")
+    parser.add_argument("--am-variant", dest="am_variant", choices=AM_VARIANTS, help="AM carrier: full, suppressed (DSB-SC) or reduced pilot")
+    parser.add_argument("--pilot-level", dest="am_pilot_level", type=float, help="Reduced-carrier AM pilot relative to full carrier (0..1)")
+    parser.add_argument("--am-demod", dest="am_demodulator", choices=AM_DEMODULATORS, help="AM demodulator used in Monte Carlo trials")
+    parser.add_argument("--diode", dest="am_diode", choices=DIODE_MODELS, help="Rectifier of the AM envelope detector")
+    parser.add_argument("--fm-integration", dest="fm_integration", choices=INTEGRATION_METHODS, help="Numerical integration of the message into the FM phase")
+    parser.add_argument("--fm-demod", dest="fm_demodulator", choices=FM_DEMODULATORS, help="FM demodulator used in Monte Carlo trials")
+    parser.add_argument("--pll-bw", dest="pll_loop_bandwidth", type=float, help="PLL loop noise bandwidth (Hz), 0 = derive from FM deviation")
+    parser.add_argument("--emphasis-tau", dest="fm_emphasis_tau", type=float, help="FM pre/de-emphasis time constant (s), 0 = off")
+    parser.add_argument("--dsbsc", dest="include_dsbsc", action="store_true", default=None, help="Also simulate DSB-SC (Costas loop receiver)")
+    parser.add_argument("--pm", dest="include_pm", action="store_true", default=None, help="Also simulate phase modulation")
+    parser.add_argument("--fm-limiter", dest="fm_limiter", action="store_true", default=None, help="Hard-limit FM before demodulating")
+    parser.add_argument("--clamp-overmod", dest="clamp_overmodulation", action="store_true", default=None, help="Clip a negative AM envelope to zero")
+    parser.add_argument("--channel-filter", dest="channel_filter", action="store_true", default=None, help="Band-pass AM/FM around the carrier before demodulating")
+    parser.add_argument("--outliers", dest="outlier_policy", choices=OUTLIER_POLICIES, help="Reject outlier trials before computing statistics")
+    parser.add_argument("--outlier-threshold", dest="outlier_threshold", type=float, help="MAD z-score limit or percentile cut per tail, 0 = default")
+    parser.add_argument("--save-detailed", dest="save_detailed", action="store_true", default=None, help="Record and save every trial's measurements")
+    parser.add_argument("--kp", "--pm-index", dest="pm_index", type=float, help="PM phase sensitivity (rad)")
+    parser.add_argument("--noise-dist", dest="noise_distribution", choices=NOISE_DISTRIBUTIONS, help="Channel noise distribution")
+    parser.add_argument("--impulse-prob", dest="impulse_probability", type=float, help="Per-sample impulse noise probability (0..1)")
+    parser.add_argument("--impulse-amp", dest="impulse_amplitude", type=float, help="Impulse noise spike amplitude")
+    parser.add_argument("--demod-cutoff", dest="demod_cutoff_hz", type=float, help="Demodulator output low-pass cutoff (Hz), 0 = 2 x message frequency")
+    parser.add_argument("--fading-doppler", dest="fading_doppler_hz", type=float, help="Rayleigh fading Doppler spread (Hz), 0 = off")
     parser.add_argument("-i", "--interactive", action="store_true", help="Prompt for parameters interactively")
     return parser
 
@@ -131,7 +371,7 @@ def interactive_prompt(defaults: SimulationParams) -> SimulationParams:
     p.duration = _prompt_float("Duration (s)", p.duration, lambda v: _positive(v, p.duration))
     p.message_freq = _prompt_float("Message frequency (Hz)", p.message_freq, lambda v: _positive(v, p.message_freq))
     p.carrier_freq = _prompt_float("Carrier frequency (Hz)", p.carrier_freq, lambda v: _positive(v, p.carrier_freq))
-    p.am_index = _prompt_float("AM index (0..1)", p.am_index, lambda v: _clamp(v, 0.0, 1.0, p.am_index))
+    p.am_index = _prompt_float("AM index (0..1, >1 overmodulates)", p.am_index, lambda v: _clamp(v, 0.0, float("inf"), p.am_index))
     p.fm_deviation = _prompt_float("FM deviation (Hz)", p.fm_deviation, lambda v: _positive(v, p.fm_deviation))
     p.snr_min = _prompt_float("SNR min (dB)", p.snr_min, lambda v: v)
     p.snr_max = _prompt_float("SNR max (dB)", p.snr_max, lambda v: v)
@@ -143,10 +383,11 @@ def interactive_prompt(defaults: SimulationParams) -> SimulationParams:
 
 
 def choose_params(args: argparse.Namespace | None = None) -> SimulationParams:
-    defaults = SimulationParams()
     if args is None:
         parser = build_arg_parser()
         args = parser.parse_args()
+    config_file = getattr(args, "config_file", None)
+    defaults = load_params_json(config_file) if config_file else SimulationParams()
     # Start from defaults, override with CLI values if provided
     p = SimulationParams(**defaults.__dict__)
     for field in p.__dataclass_fields__.keys():
@@ -161,17 +402,35 @@ def choose_params(args: argparse.Namespace | None = None) -> SimulationParams:
 
 
 def summarize_params(p: SimulationParams) -> str:
-    snr_range = _format_snr_range(p.snr_min, p.snr_max, p.snr_step)
+    if p.snr_values:
+        snr_range = ", ".join(str(round(v, 3)) for v in p.snr_values)
+    else:
+        snr_range = _format_snr_range(p.snr_min, p.snr_max, p.snr_step)
     return (
         "Parameters:"\
         f"\n  fs: {p.sampling_rate:.3f} Hz"\
         f"\n  duration: {p.duration:.6f} s"\
         f"\n  fm: {p.message_freq:.3f} Hz, Am: {p.message_amplitude:.3f}"\
         f"\n  fc: {p.carrier_freq:.3f} Hz, Ac: {p.carrier_amplitude:.3f}"\
-        f"\n  AM index ka: {p.am_index:.3f}"\
-        f"\n  FM deviation: {p.fm_deviation:.3f} Hz"\
+        f"\n  AM index ka: {p.am_index:.3f}{' (overmodulated' + (', clamped)' if p.clamp_overmodulation else ')') if is_overmodulated(p) else ''}"\
+        f"\n  AM variant: {p.am_variant}{' (pilot ' + format(p.am_pilot_level, '.3f') + ')' if p.am_variant == 'reduced-carrier' else ''}"\
+        f"\n  AM demodulator: {am_receiver(p)}{' (' + p.am_diode + ' diode)' if am_receiver(p) == 'envelope' else ''}"\
+        f"\n  FM deviation: {p.fm_deviation:.3f} Hz ({p.fm_integration} integration)"\
+        f"\n  FM demodulator: {p.fm_demodulator}{' with limiter' if p.fm_limiter else ''}"\
+        f"\n  Channel filter: {'on' if p.channel_filter else 'off'}"\
+        f"\n  Demod cutoff: {p.demod_cutoff_hz or 2.0 * p.message_freq:.1f} Hz"\
+        f"\n  FM emphasis tau: {p.fm_emphasis_tau * 1e6:.1f} us"\
+        f"\n  DSB-SC: {'on' if p.include_dsbsc else 'off'}"\
+        f"\n  PM: {'on, kp=' + format(p.pm_index, '.3f') if p.include_pm else 'off'}"\
+        f"\n  Noise distribution: {p.noise_distribution}"\
+        f"\n  Impulse noise: {format(p.impulse_probability, 'g') + ' @ ' + format(p.impulse_amplitude, '.2f') if p.impulse_probability > 0 else 'off'}"\
+        f"\n  Extra schemes: {', '.join(p.extra_modulations) or 'none'}"\
+        f"\n  Rayleigh fading: {format(p.fading_doppler_hz, '.1f') + ' Hz Doppler' if p.fading_doppler_hz > 0 else 'off'}"\
         f"\n  SNR range (dB): {snr_range}"\
-        f"\n  trials: {p.trials}"
+        f"\n  trials: {p.trials} (seed {p.seed}, workers {p.workers})"\
+        f"\n  Convergence: {'standard error < ' + format(p.convergence_tolerance, 'g') + ' dB, up to ' + str(p.max_trials or 10 * p.trials) + ' trials' if p.convergence_tolerance > 0 else 'off'}"\
+        f"\n  Outlier rejection: {p.outlier_policy + (' @ ' + format(p.outlier_threshold, 'g') if p.outlier_threshold > 0 else '') if p.outlier_policy != 'none' else 'off'}"\
+        f"\n  Detailed trials: {'on' if p.save_detailed else 'off'}"
     )
 
 
@@ -1,12 +1,68 @@
 from __future__ import annotations
 
+import math
+from dataclasses import dataclass
+from typing import Tuple
+
 import numpy as np
 from scipy import signal
 
+from filters import butterworth_lowpass, design_fir_lowpass, rc_lowpass
+from signals import fm_sensitivity
+
+
+def unwrap_phase(phase: np.ndarray) -> np.ndarray:
+    """Remove 2π discontinuities wherever consecutive samples jump by more than π."""
+    phase = np.asarray(phase, dtype=float)
+    if len(phase) < 2:
+        return phase.copy()
+    jumps = np.diff(phase)
+    corrections = -2.0 * np.pi * np.round(jumps / (2.0 * np.pi)) * (np.abs(jumps) > np.pi)
+    return phase + np.concatenate(([0.0], np.cumsum(corrections)))
+
+
+def instantaneous_phase(x: np.ndarray) -> np.ndarray:
+    """Continuous (unwrapped) phase of the analytic signal, in radians."""
+    return unwrap_phase(np.angle(signal.hilbert(x)))
+
+
+def instantaneous_frequency(x: np.ndarray, sampling_rate: float) -> np.ndarray:
+    """Instantaneous frequency in Hz: the scaled derivative of the unwrapped phase."""
+    return np.gradient(instantaneous_phase(x)) * sampling_rate / (2.0 * np.pi)
+
+
+DIODE_MODELS = ("full-wave", "half-wave", "exponential")
+
+
+def diode_rectify(x: np.ndarray, model: str = "full-wave", knee_voltage: float = 0.05) -> np.ndarray:
+    """
+    Rectifier stage of an envelope detector.
+    
+    "full-wave" is |x|, "half-wave" an ideal diode max(x, 0), and
+    "exponential" a diode with a soft knee, knee_voltage * ln(1 + exp(x / knee_voltage)),
+    which conducts a little below zero and approaches half-wave as the knee
+    voltage shrinks relative to the signal.
+    
+    Raises:
+        ValueError: If model is not one of DIODE_MODELS or knee_voltage is not positive
+    """
+    x = np.asarray(x, dtype=float)
+    if model == "full-wave":
+        return np.abs(x)
+    if model == "half-wave":
+        return np.maximum(x, 0.0)
+    if model == "exponential":
+        if not knee_voltage > 0:
+            raise ValueError(f"Knee voltage must be positive, got {knee_voltage}")
+        return knee_voltage * np.logaddexp(0.0, x / knee_voltage)
+    raise ValueError(f"Unknown diode model {model!r}, expected one of {', '.join(DIODE_MODELS)}")
+
 
 def am_demodulate_envelope(am_signal: np.ndarray, t: np.ndarray, carrier_freq: float, 
                           carrier_amplitude: float = 1.0, smoothing: bool = True,
-                          message_freq: float | None = None) -> np.ndarray:
+                          message_freq: float | None = None,
+                          cutoff_hz: float | None = None, diode: str = "full-wave",
+                          rc_time_constant: float | None = None) -> np.ndarray:
     """
     AM demodulation using envelope detection.
     
@@ -16,24 +72,35 @@ def am_demodulate_envelope(am_signal: np.ndarray, t: np.ndarray, carrier_freq: f
         carrier_freq: Carrier frequency (for optional filtering)
         carrier_amplitude: Expected carrier amplitude
         smoothing: Whether to apply low-pass filtering
+        message_freq: If provided (and no cutoff_hz), smooth at ~2.5*fm
+        cutoff_hz: Explicit smoothing cutoff in Hz, independent of the sampling rate
+        diode: Rectifier from DIODE_MODELS; single-diode models are scaled
+            by 2 so every model has the full-wave message gain
+        rc_time_constant: If given, smooth with an RC low-pass (seconds)
+            like a physical detector instead of the Butterworth filter
     
     Returns:
         Demodulated message signal
     """
-    # Envelope detection: absolute value
-    envelope = np.abs(am_signal)
+    # Envelope detection: rectify, then smooth away the carrier
+    envelope = diode_rectify(am_signal, diode)
+    if diode != "full-wave":
+        envelope = 2.0 * envelope
     
-    if smoothing:
+    if smoothing and rc_time_constant is not None:
+        envelope = rc_lowpass(envelope, 1.0 / np.mean(np.diff(t)), rc_time_constant)
+    elif smoothing:
         # Low-pass to message band; if message_freq provided, prefer ~2.5*fm
         nyquist = 1.0 / (2.0 * np.mean(np.diff(t)))
-        if message_freq is not None:
+        if cutoff_hz is not None:
+            cutoff_freq = min(0.45 * nyquist, float(cutoff_hz))
+        elif message_freq is not None:
             cutoff_freq = min(0.45 * nyquist, 2.5 * float(message_freq))
         else:
             cutoff_freq = min(0.45 * nyquist, carrier_freq / 5.0)
         normalized_cutoff = cutoff_freq / nyquist
         if 0.0 < normalized_cutoff < 1.0:
-            b, a = signal.butter(4, normalized_cutoff, btype='low')
-            envelope = signal.filtfilt(b, a, envelope)
+            envelope = butterworth_lowpass(envelope, 2.0 * nyquist, cutoff_freq, 4, zero_phase=True)
     
     # Remove DC offset and scale
     envelope = envelope - np.mean(envelope)
@@ -42,16 +109,63 @@ def am_demodulate_envelope(am_signal: np.ndarray, t: np.ndarray, carrier_freq: f
     return envelope
 
 
+def am_demodulate_hilbert(am_signal: np.ndarray, t: np.ndarray, carrier_freq: float,
+                          carrier_amplitude: float = 1.0,
+                          message_freq: float | None = None,
+                          cutoff_hz: float | None = None) -> np.ndarray:
+    """
+    AM demodulation using the magnitude of the analytic signal.
+
+    Unlike rectification plus low-pass filtering, |hilbert(s)| tracks
+    Ac*(1 + ka*m(t)) directly, so no carrier-dependent smoothing is needed.
+
+    Args:
+        am_signal: AM modulated signal
+        t: Time vector
+        carrier_freq: Carrier frequency (kept for interface parity)
+        carrier_amplitude: Expected carrier amplitude
+        message_freq: If provided, low-pass the envelope to ~2.5*fm to reject noise
+        cutoff_hz: Explicit low-pass cutoff in Hz (takes precedence over message_freq)
+
+    Returns:
+        Demodulated message signal
+    """
+    envelope = np.abs(signal.hilbert(am_signal))
+
+    if cutoff_hz is not None or message_freq is not None:
+        nyquist = 1.0 / (2.0 * np.mean(np.diff(t)))
+        target = cutoff_hz if cutoff_hz is not None else 2.5 * float(message_freq)
+        cutoff_freq = min(0.45 * nyquist, float(target))
+        if 0.0 < cutoff_freq / nyquist < 1.0:
+            envelope = butterworth_lowpass(envelope, 2.0 * nyquist, cutoff_freq, 4, zero_phase=True)
+
+    # Remove DC offset (the unmodulated carrier level) and scale
+    envelope = envelope - np.mean(envelope)
+    envelope = envelope / carrier_amplitude
+
+    return envelope
+
+
 def fm_demodulate_instantaneous_frequency(fm_signal: np.ndarray, t: np.ndarray, 
-                                        carrier_freq: float, fm_deviation: float) -> np.ndarray:
+                                        carrier_freq: float, fm_deviation: float,
+                                        cutoff_hz: float | None = None,
+                                        num_taps: int | None = None) -> np.ndarray:
     """
     FM demodulation using instantaneous frequency estimation.
     
+    The discriminator itself (a central difference of the phase) adds no
+    delay. The output low-pass is either a zero-phase Butterworth or, with
+    num_taps, a causal linear-phase FIR whose (num_taps-1)/2 sample group
+    delay is removed, so in both cases the output lines up with the message
+    without any alignment search.
+    
     Args:
         fm_signal: FM modulated signal
         t: Time vector
         carrier_freq: Carrier frequency
-        fm_deviation: FM frequency deviation
+        fm_deviation: FM frequency deviation in Hz per unit message (see fm_sensitivity)
+        cutoff_hz: If provided, low-pass the output to this cutoff in Hz
+        num_taps: Use a windowed-sinc FIR of this length for the low-pass
     
     Returns:
         Demodulated message signal
@@ -59,26 +173,100 @@ def fm_demodulate_instantaneous_frequency(fm_signal: np.ndarray, t: np.ndarray,
     # Method 1: Quadrature demodulation (Hilbert transform approach)
     # This is more robust than simple differentiation
     
-    # Create analytic signal using Hilbert transform
-    analytic_signal = signal.hilbert(fm_signal)
+    # Instantaneous frequency from the unwrapped phase of the analytic signal
+    dt = np.mean(np.diff(t))
+    instantaneous_freq = instantaneous_frequency(fm_signal, 1.0 / dt)
     
-    # Calculate instantaneous phase
-    phase = np.angle(analytic_signal)
+    # Remove the carrier; the offset in rad/s is kf * m(t)
+    angular_deviation = 2.0 * np.pi * (instantaneous_freq - carrier_freq)
+    message = angular_deviation / fm_sensitivity(fm_deviation)
     
-    # Unwrap phase to avoid 2π jumps
-    phase_unwrapped = np.unwrap(phase)
+    if cutoff_hz is not None:
+        fs = 1.0 / dt
+        cutoff = min(0.45 * fs, float(cutoff_hz))
+        if num_taps is not None:
+            message = design_fir_lowpass(cutoff, fs, num_taps).apply(message)
+        else:
+            message = butterworth_lowpass(message, fs, cutoff, 4, zero_phase=True)
     
-    # Calculate instantaneous frequency as derivative of phase
-    dt = np.mean(np.diff(t))
-    instantaneous_freq = np.gradient(phase_unwrapped) / (2.0 * np.pi * dt)
+    return message
+
+
+def pm_demodulate(pm_signal: np.ndarray, t: np.ndarray, carrier_freq: float,
+                  pm_index: float = 1.0) -> np.ndarray:
+    """
+    PM demodulation from the unwrapped phase of the analytic signal.
+    
+    Args:
+        pm_signal: PM modulated signal
+        t: Time vector
+        carrier_freq: Carrier frequency
+        pm_index: Phase sensitivity kp (rad per unit message amplitude)
+    
+    Returns:
+        Demodulated message signal
+    """
+    phase_unwrapped = instantaneous_phase(pm_signal)
     
-    # Remove carrier frequency to get frequency deviation
-    freq_deviation = instantaneous_freq - carrier_freq
+    # Remove the carrier ramp; the constant -π/2 (sin vs. cos) goes with the mean
+    phase_deviation = phase_unwrapped - 2.0 * np.pi * carrier_freq * t
+    phase_deviation = phase_deviation - np.mean(phase_deviation)
     
-    # Convert frequency deviation to message signal
-    message = freq_deviation / fm_deviation
+    return phase_deviation / pm_index
+
+
+def de_emphasis(demodulated: np.ndarray, sampling_rate: float, tau: float = 75e-6) -> np.ndarray:
+    """
+    First-order de-emphasis low-pass, H(s) = 1 / (1 + s*tau).
+
+    Exact inverse of signals.pre_emphasis, so applied after FM demodulation it
+    restores the message while attenuating the high-frequency (parabolic) noise.
+
+    Args:
+        demodulated: Demodulated message signal
+        sampling_rate: Sampling rate in Hz
+        tau: Time constant in seconds (75 µs is the broadcast standard)
+
+    Returns:
+        De-emphasized message signal
+    """
+    dt = 1.0 / float(sampling_rate)
+    alpha = dt / (dt + tau)
+    x = np.asarray(demodulated, dtype=float)
+    if len(x) == 0:
+        return x.copy()
+    # y[n] = alpha*x[n] + (1 - alpha)*y[n-1], started at y[-1] = x[0]
+    y, _ = signal.lfilter([alpha], [1.0, alpha - 1.0], x, zi=[(1.0 - alpha) * x[0]])
+    return y
+
+
+def estimate_frequency_offset(x: np.ndarray, sampling_rate: float, carrier_freq: float) -> float:
+    """
+    Carrier frequency offset of a real passband signal, in Hz.
+
+    The phase of the lag-1 autocorrelation of the analytic signal is the
+    power-weighted mean frequency. AM, DSB-SC and FM by a zero-mean message
+    all have spectra symmetric about the carrier, so that mean minus
+    carrier_freq is the offset.
+    """
+    z = signal.hilbert(np.asarray(x, dtype=float))
+    if len(z) < 2:
+        raise ValueError("Need at least two samples to estimate a frequency")
+    mean_freq = np.angle(np.sum(z[1:] * np.conj(z[:-1]))) * sampling_rate / (2.0 * np.pi)
+    return float(mean_freq - carrier_freq)
+
+
+def hard_limiter(x: np.ndarray, threshold: float) -> np.ndarray:
+    """
+    Clip a signal to +/-threshold.
     
-    return message
+    With a threshold well below the carrier amplitude almost every sample
+    is clipped and the output approaches threshold * sign(x), discarding
+    the amplitude variations an FM receiver should ignore.
+    """
+    if threshold <= 0:
+        raise ValueError("Limiter threshold must be positive")
+    return np.clip(np.asarray(x, dtype=float), -threshold, threshold)
 
 
 def fm_demodulate_quadrature(fm_signal: np.ndarray, t: np.ndarray, 
@@ -90,7 +278,7 @@ def fm_demodulate_quadrature(fm_signal: np.ndarray, t: np.ndarray,
         fm_signal: FM modulated signal
         t: Time vector
         carrier_freq: Carrier frequency
-        fm_deviation: FM frequency deviation
+        fm_deviation: FM frequency deviation in Hz per unit message (see fm_sensitivity)
     
     Returns:
         Demodulated message signal
@@ -99,27 +287,498 @@ def fm_demodulate_quadrature(fm_signal: np.ndarray, t: np.ndarray,
     in_phase = fm_signal * np.cos(2.0 * np.pi * carrier_freq * t)
     quadrature = fm_signal * np.sin(2.0 * np.pi * carrier_freq * t)
     
-    # Low-pass filter to remove high-frequency components
-    cutoff_freq = carrier_freq / 10.0
+    # Low-pass filter to remove the 2*fc mixing products
+    cutoff_freq = carrier_freq / 2.0
     nyquist = 1.0 / (2.0 * np.mean(np.diff(t)))
     normalized_cutoff = cutoff_freq / nyquist
     
     if normalized_cutoff < 1.0:
-        b, a = signal.butter(4, normalized_cutoff, btype='low')
-        in_phase = signal.filtfilt(b, a, in_phase)
-        quadrature = signal.filtfilt(b, a, quadrature)
+        in_phase = butterworth_lowpass(in_phase, 2.0 * nyquist, cutoff_freq, 4, zero_phase=True)
+        quadrature = butterworth_lowpass(quadrature, 2.0 * nyquist, cutoff_freq, 4, zero_phase=True)
     
-    # Calculate instantaneous frequency
-    # d/dt(arctan(Q/I)) = (I*dQ/dt - Q*dI/dt) / (I^2 + Q^2)
+    # The mixer output is already at baseband: for s = sin(wc*t + psi),
+    # I ~ sin(psi)/2 and Q ~ cos(psi)/2, so psi = arctan(I/Q) and
+    # d(psi)/dt = (Q*dI/dt - I*dQ/dt) / (I^2 + Q^2) rad/s = kf * m(t)
     dt = np.mean(np.diff(t))
     dI_dt = np.gradient(in_phase) / dt
     dQ_dt = np.gradient(quadrature) / dt
     
-    instantaneous_freq = (in_phase * dQ_dt - quadrature * dI_dt) / (in_phase**2 + quadrature**2 + 1e-10)
-    instantaneous_freq = instantaneous_freq / (2.0 * np.pi)
-    
-    # Remove carrier frequency and scale
-    freq_deviation = instantaneous_freq - carrier_freq
-    message = freq_deviation / fm_deviation
+    angular_deviation = (quadrature * dI_dt - in_phase * dQ_dt) / (in_phase**2 + quadrature**2 + 1e-10)
+    message = angular_deviation / fm_sensitivity(fm_deviation)
     
     return message
+
+
+def _loop_gains(loop_bandwidth: float, damping: float, dt: float) -> Tuple[float, float]:
+    # Proportional/integral gains of a type-2 loop with unit phase-detector gain
+    wn = 2.0 * np.pi * loop_bandwidth / (damping + 1.0 / (4.0 * damping))
+    return 2.0 * damping * wn * dt, (wn * dt) ** 2
+
+
+def fm_demodulate_pll(fm_signal: np.ndarray, t: np.ndarray, carrier_freq: float,
+                      fm_deviation: float, loop_bandwidth: float | None = None,
+                      damping: float = 0.707) -> np.ndarray:
+    """
+    FM demodulation using a second-order (type-2) phase-locked loop.
+
+    The phase detector compares the analytic signal against the NCO phase,
+    a proportional-integral loop filter drives the NCO, and the loop filter
+    output (the NCO frequency control) is the recovered message.
+
+    The loop gains follow from the noise bandwidth Bn and the sampling rate:
+    wn = 2*pi*Bn / (zeta + 1/(4*zeta)), Kp = 2*zeta*wn*T, Ki = (wn*T)^2.
+    Lock is acquired in roughly 4 / (zeta*wn) seconds (about 0.2 ms for the
+    default Bn of 5 kHz), since the NCO starts at the carrier phase.
+
+    Args:
+        fm_signal: FM modulated signal
+        t: Time vector
+        carrier_freq: Carrier frequency (NCO free-running frequency)
+        fm_deviation: FM frequency deviation in Hz per unit message (see fm_sensitivity)
+        loop_bandwidth: Loop noise bandwidth Bn in Hz (defaults to fm_deviation)
+        damping: Loop damping factor zeta
+
+    Returns:
+        Demodulated message signal
+    """
+    dt = float(np.mean(np.diff(t)))
+    fs = 1.0 / dt
+    if loop_bandwidth is None or loop_bandwidth <= 0:
+        loop_bandwidth = fm_deviation
+    kp, ki = _loop_gains(min(float(loop_bandwidth), 0.1 * fs), damping, dt)
+
+    analytic_signal = signal.hilbert(fm_signal)
+    in_phase = analytic_signal.real.tolist()
+    quadrature = analytic_signal.imag.tolist()
+
+    nco_step = 2.0 * np.pi * carrier_freq * dt
+    theta = math.atan2(quadrature[0], in_phase[0])
+    integrator = 0.0
+    control = np.zeros(len(in_phase))
+
+    for n in range(len(in_phase)):
+        cos_t = math.cos(theta)
+        sin_t = math.sin(theta)
+        # Phase error = angle(z * exp(-j*theta))
+        error = math.atan2(quadrature[n] * cos_t - in_phase[n] * sin_t,
+                           in_phase[n] * cos_t + quadrature[n] * sin_t)
+        integrator += ki * error
+        control[n] = kp * error + integrator
+        theta += nco_step + control[n]
+        if theta > np.pi:
+            theta -= 2.0 * np.pi
+
+    # NCO frequency offset (rad/sample) -> rad/s -> message units
+    message = control * fs / fm_sensitivity(fm_deviation)
+
+    return message
+
+
+def am_demodulate_coherent(am_signal: np.ndarray, t: np.ndarray, carrier_freq: float,
+                           carrier_amplitude: float = 1.0, message_freq: float | None = None,
+                           cutoff_hz: float | None = None, loop_bandwidth: float | None = None,
+                           damping: float = 0.707) -> np.ndarray:
+    """
+    Synchronous (coherent) AM detection with PLL carrier recovery.
+
+    A narrow type-2 PLL locks to the carrier pilot of the analytic signal;
+    mixing with the recovered carrier gives in-phase and quadrature arms.
+    Any residual static phase is removed by rotating the arms onto the
+    direction of maximum power, with the sign chosen so the carrier (DC)
+    term is positive. Only in-phase noise reaches the output, and unlike
+    envelope detection over-modulation does not fold the message.
+
+    Args:
+        am_signal: AM modulated signal
+        t: Time vector
+        carrier_freq: Nominal carrier frequency
+        carrier_amplitude: Expected carrier amplitude
+        message_freq: If provided (and no cutoff_hz), low-pass to ~2.5*fm
+        cutoff_hz: Explicit low-pass cutoff in Hz
+        loop_bandwidth: PLL noise bandwidth in Hz (defaults to 1% of carrier_freq)
+        damping: Loop damping factor zeta
+
+    Returns:
+        Demodulated message signal
+    """
+    dt = float(np.mean(np.diff(t)))
+    if loop_bandwidth is None or loop_bandwidth <= 0:
+        loop_bandwidth = 0.01 * carrier_freq
+    kp, ki = _loop_gains(float(loop_bandwidth), damping, dt)
+
+    analytic_signal = signal.hilbert(am_signal)
+    # Linear detector q/|z|: averaged over m(t) its gain stays positive even
+    # when over-modulation drives the envelope negative
+    level = float(np.mean(np.abs(analytic_signal))) or 1.0
+    real_part = analytic_signal.real.tolist()
+    imag_part = analytic_signal.imag.tolist()
+
+    lo_step = 2.0 * np.pi * carrier_freq * dt
+    theta = math.atan2(imag_part[0], real_part[0])
+    integrator = 0.0
+    baseband = np.zeros(len(real_part), dtype=complex)
+
+    for n in range(len(real_part)):
+        cos_t = math.cos(theta)
+        sin_t = math.sin(theta)
+        i_arm = real_part[n] * cos_t + imag_part[n] * sin_t
+        q_arm = imag_part[n] * cos_t - real_part[n] * sin_t
+        baseband[n] = complex(i_arm, q_arm)
+        error = q_arm / level
+        integrator += ki * error
+        theta += lo_step + kp * error + integrator
+        if theta > np.pi:
+            theta -= 2.0 * np.pi
+
+    # Rotate onto the principal axis: angle of the second moment halved, then
+    # resolve the remaining 180 degree ambiguity with the carrier's DC sign
+    rotation = np.exp(-0.5j * np.angle(np.mean(baseband ** 2)))
+    in_phase = (baseband * rotation).real
+    if np.mean(in_phase) < 0:
+        in_phase = -in_phase
+
+    if cutoff_hz is not None or message_freq is not None:
+        nyquist = 0.5 / dt
+        target = cutoff_hz if cutoff_hz is not None else 2.5 * float(message_freq)
+        cutoff_freq = min(0.45 * nyquist, float(target))
+        if 0.0 < cutoff_freq / nyquist < 1.0:
+            in_phase = butterworth_lowpass(in_phase, 2.0 * nyquist, cutoff_freq, 4, zero_phase=True)
+
+    # Remove the carrier level and scale
+    return (in_phase - np.mean(in_phase)) / carrier_amplitude
+
+
+def dsbsc_demodulate_costas(dsb_signal: np.ndarray, t: np.ndarray, carrier_freq: float,
+                            carrier_amplitude: float = 1.0, message_freq: float | None = None,
+                            loop_bandwidth: float | None = None, damping: float = 0.707,
+                            cutoff_hz: float | None = None) -> np.ndarray:
+    """
+    DSB-SC demodulation with a Costas loop for carrier recovery.
+
+    The received signal is mixed with the local oscillator into in-phase and
+    quadrature arms; their product (~ sin(2*phase_error)) drives a PI loop
+    filter that steers the oscillator. The in-phase arm is the message.
+    Like any Costas loop the recovered message has a 180 degree ambiguity; the
+    oscillator starts at zero phase so it locks to the nearer solution.
+
+    Args:
+        dsb_signal: DSB-SC modulated signal
+        t: Time vector
+        carrier_freq: Nominal carrier frequency
+        carrier_amplitude: Expected carrier amplitude
+        message_freq: If provided (and no cutoff_hz), low-pass the in-phase arm to ~2.5*fm
+        loop_bandwidth: Loop noise bandwidth in Hz (defaults to 2% of carrier_freq)
+        damping: Loop damping factor zeta
+        cutoff_hz: Explicit low-pass cutoff in Hz
+
+    Returns:
+        Demodulated message signal
+    """
+    dt = float(np.mean(np.diff(t)))
+    if loop_bandwidth is None or loop_bandwidth <= 0:
+        loop_bandwidth = 0.02 * carrier_freq
+    kp, ki = _loop_gains(float(loop_bandwidth), damping, dt)
+
+    analytic_signal = signal.hilbert(dsb_signal)
+    # Normalize the detector by the average arm power so loop gain does not depend on m(t)
+    arm_power = float(np.mean(np.abs(analytic_signal) ** 2)) or 1.0
+    real_part = analytic_signal.real.tolist()
+    imag_part = analytic_signal.imag.tolist()
+
+    lo_step = 2.0 * np.pi * carrier_freq * dt
+    theta = 0.0
+    integrator = 0.0
+    in_arm = np.zeros(len(real_part))
+
+    for n in range(len(real_part)):
+        cos_t = math.cos(theta)
+        sin_t = math.sin(theta)
+        i_arm = real_part[n] * cos_t + imag_part[n] * sin_t
+        q_arm = imag_part[n] * cos_t - real_part[n] * sin_t
+        in_arm[n] = i_arm
+        error = i_arm * q_arm / arm_power
+        integrator += ki * error
+        theta += lo_step + kp * error + integrator
+        if theta > np.pi:
+            theta -= 2.0 * np.pi
+
+    message = in_arm
+    if cutoff_hz is not None or message_freq is not None:
+        nyquist = 0.5 / dt
+        target = cutoff_hz if cutoff_hz is not None else 2.5 * float(message_freq)
+        cutoff_freq = min(0.45 * nyquist, float(target))
+        if 0.0 < cutoff_freq / nyquist < 1.0:
+            message = butterworth_lowpass(message, 2.0 * nyquist, cutoff_freq, 4, zero_phase=True)
+
+    return message / carrier_amplitude
+
+
+def recover_symbol_timing(x: np.ndarray, samples_per_symbol: float, loop_gain: float = 0.1) -> np.ndarray:
+    """
+    Symbol timing recovery with a Gardner timing-error detector.
+
+    A first-order loop steps one symbol at a time, reading the signal at
+    fractional positions by linear interpolation. The Gardner error
+    Re{(y[k] - y[k-1]) * conj(y[k-1/2])} is zero when y[k-1/2] sits on the
+    zero crossing between symbols, i.e. when y[k] is at the symbol centre,
+    so fractional timing offsets are tracked out. The detector needs
+    band-limited symbols with transitions (flat rectangular pulses give no
+    timing information).
+
+    Args:
+        x: Real or complex baseband signal
+        samples_per_symbol: Nominal samples per symbol (may be fractional)
+        loop_gain: Fraction of the timing error corrected per symbol
+
+    Returns:
+        Sample indices of the recovered symbol centres
+    """
+    x = np.asarray(x)
+    sps = float(samples_per_symbol)
+    if sps < 2:
+        raise ValueError("Need at least two samples per symbol")
+    n = np.arange(len(x))
+    # Normalize so the loop gain does not depend on signal level
+    scale = float(np.mean(np.abs(x) ** 2)) or 1.0
+
+    def sample(position: float):
+        return np.interp(position, n, x.real) + 1j * np.interp(position, n, x.imag) \
+            if np.iscomplexobj(x) else np.interp(position, n, x)
+
+    positions = []
+    position = sps
+    while position <= len(x) - 1:
+        current = sample(position)
+        error = np.real((current - sample(position - sps)) * np.conj(sample(position - 0.5 * sps))) / scale
+        positions.append(position)
+        position += sps - loop_gain * sps * error
+    return np.round(np.array(positions)).astype(int)
+
+
+def _symbol_start_offset(centres: np.ndarray, samples_per_symbol: int) -> int:
+    # Circular mean of the symbol start phase implied by the recovered centres
+    if len(centres) == 0:
+        return 0
+    # Skip the loop's acquisition phase when there are enough symbols
+    settled = centres[len(centres) // 4:] if len(centres) >= 8 else centres
+    phase = 2.0 * np.pi * (settled - 0.5 * samples_per_symbol) / samples_per_symbol
+    mean_phase = np.angle(np.mean(np.exp(1j * phase)))
+    return int(np.round(mean_phase * samples_per_symbol / (2.0 * np.pi))) % samples_per_symbol
+
+
+def matched_filter(x: np.ndarray, template: np.ndarray) -> np.ndarray:
+    """
+    Filter x with the time-reversed template.
+
+    Output sample n is the correlation of the template with the len(template)
+    samples ending at n, so for back-to-back symbols the decision samples
+    are n = (k+1)*len(template) - 1. The output has the length of x.
+    """
+    x = np.asarray(x, dtype=float)
+    template = np.asarray(template, dtype=float)
+    if len(template) == 0:
+        raise ValueError("Template must not be empty")
+    return np.convolve(x, template[::-1])[:len(x)]
+
+
+FSK_DETECTORS = ("correlator", "matched")
+
+
+def fsk_demodulate(fsk_signal: np.ndarray, sampling_rate: float, bit_rate: float,
+                   freq_space: float, freq_mark: float, timing_recovery: bool = False,
+                   detector: str = "correlator") -> np.ndarray:
+    """
+    Binary FSK demodulation.
+
+    The "correlator" detector is non-coherent: each bit interval is
+    correlated against complex tones at the space and mark frequencies and
+    the bit is 1 when the mark correlator has more energy.
+
+    The "matched" detector is coherent: the signal is matched-filtered with
+    one bit period of each tone, with the phase fsk_modulate gives it, and
+    the outputs are compared at the end of each bit. This needs the phase
+    to restart every bit, i.e. both tones complete a whole number of cycles
+    per bit, and the bit boundaries to start at sample 0.
+
+    Args:
+        fsk_signal: FSK modulated signal
+        sampling_rate: Sampling rate in Hz
+        bit_rate: Bit rate in bits/s
+        freq_space: Tone frequency for bit 0
+        freq_mark: Tone frequency for bit 1
+        timing_recovery: Find the bit boundaries with recover_symbol_timing on
+            the instantaneous frequency instead of assuming they start at sample 0
+        detector: One of FSK_DETECTORS
+
+    Returns:
+        Array of detected bits (0/1)
+    """
+    if detector not in FSK_DETECTORS:
+        raise ValueError(f"Unknown FSK detector {detector!r}, expected one of {', '.join(FSK_DETECTORS)}")
+    samples_per_bit = int(np.round(sampling_rate / bit_rate))
+    if detector == "matched":
+        cycles = np.array([freq_space, freq_mark]) * samples_per_bit / sampling_rate
+        if timing_recovery or not np.allclose(cycles, np.round(cycles), atol=1e-6):
+            raise ValueError("Matched FSK detection needs whole tone cycles per bit and no timing recovery")
+    if timing_recovery:
+        discriminator = instantaneous_frequency(fsk_signal, sampling_rate) - 0.5 * (freq_space + freq_mark)
+        # Clip spikes where the signal vanishes so they cannot dominate the loop normalization
+        spacing = abs(freq_mark - freq_space)
+        discriminator = np.clip(discriminator, -spacing, spacing)
+        discriminator = butterworth_lowpass(discriminator, sampling_rate, min(bit_rate, 0.45 * sampling_rate),
+                                            4, zero_phase=True)
+        offset = _symbol_start_offset(recover_symbol_timing(discriminator, samples_per_bit), samples_per_bit)
+        fsk_signal = fsk_signal[offset:]
+    num_bits = len(fsk_signal) // samples_per_bit
+    if num_bits == 0:
+        return np.zeros(0, dtype=int)
+
+    if detector == "matched":
+        # fsk_modulate's phase at sample n of a bit is 2*pi*f*(n+1)/fs
+        k = np.arange(1, samples_per_bit + 1) / sampling_rate
+        decisions = np.arange(1, num_bits + 1) * samples_per_bit - 1
+        space_out = matched_filter(fsk_signal, np.sin(2.0 * np.pi * freq_space * k))[decisions]
+        mark_out = matched_filter(fsk_signal, np.sin(2.0 * np.pi * freq_mark * k))[decisions]
+        return (mark_out > space_out).astype(int)
+
+    n = np.arange(samples_per_bit) / sampling_rate
+    space_ref = np.exp(-2j * np.pi * freq_space * n)
+    mark_ref = np.exp(-2j * np.pi * freq_mark * n)
+
+    symbols = np.asarray(fsk_signal[:num_bits * samples_per_bit], dtype=float).reshape(num_bits, samples_per_bit)
+    space_energy = np.abs(symbols @ space_ref)
+    mark_energy = np.abs(symbols @ mark_ref)
+    return (mark_energy > space_energy).astype(int)
+
+
+@dataclass
+class IQImbalance:
+    """
+    Gain and phase mismatch of a quadrature receiver's Q branch against I.
+
+    The Q branch sees gain 10^(gain_db/20) and a local oscillator off by
+    phase_deg from quadrature, so Q' = g (Q cos(phi) + I sin(phi)); I is
+    the reference and passes unchanged.
+    """
+    gain_db: float = 0.0
+    phase_deg: float = 0.0
+
+    def apply(self, iq: np.ndarray) -> np.ndarray:
+        iq = np.asarray(iq, dtype=complex)
+        gain = 10.0 ** (self.gain_db / 20.0)
+        phi = np.deg2rad(self.phase_deg)
+        return iq.real + 1j * gain * (iq.imag * np.cos(phi) + iq.real * np.sin(phi))
+
+
+def correct_iq_imbalance(iq: np.ndarray) -> np.ndarray:
+    """
+    Blind I/Q imbalance correction by Gram-Schmidt orthogonalization.
+
+    Assumes the transmitted I and Q are uncorrelated with equal power, as
+    for square QAM: the part of Q correlated with I is removed and what is
+    left is rescaled to the power of I.
+    """
+    iq = np.asarray(iq, dtype=complex)
+    i, q = iq.real, iq.imag
+    power_i = np.mean(i * i)
+    if len(iq) == 0 or power_i == 0:
+        return iq.copy()
+    q = q - (np.mean(i * q) / power_i) * i
+    power_q = np.mean(q * q)
+    if power_q > 0:
+        q = q * np.sqrt(power_i / power_q)
+    return i + 1j * q
+
+
+def qam_soft_symbols(qam_signal: np.ndarray, sampling_rate: float, symbol_rate: float,
+                     carrier_freq: float, carrier_amplitude: float = 1.0,
+                     timing_recovery: bool = False) -> np.ndarray:
+    """
+    Coherent (I, Q) estimate of each QAM symbol, before any decision.
+
+    Over each symbol interval the received samples are fitted (least squares)
+    to I*cos(2*pi*fc*t) - Q*sin(2*pi*fc*t), which stays exact even when a
+    symbol does not span a whole number of carrier cycles.
+
+    Args:
+        qam_signal: QAM modulated signal
+        sampling_rate: Sampling rate in Hz
+        symbol_rate: Symbol rate in symbols/s
+        carrier_freq: Carrier frequency (assumed phase-synchronous)
+        carrier_amplitude: Expected carrier amplitude
+        timing_recovery: Find the symbol boundaries with recover_symbol_timing
+            on the low-passed complex baseband instead of assuming sample 0
+
+    Returns:
+        Complex I + jQ estimates, one per whole symbol
+    """
+    samples_per_symbol = int(np.round(sampling_rate / symbol_rate))
+    qam_signal = np.asarray(qam_signal, dtype=float)
+    offset = 0
+    if timing_recovery:
+        t_all = np.arange(len(qam_signal)) / sampling_rate
+        mixed = 2.0 * qam_signal * np.exp(-2j * np.pi * carrier_freq * t_all)
+        cutoff = min(symbol_rate, 0.45 * sampling_rate)
+        baseband = (butterworth_lowpass(mixed.real, sampling_rate, cutoff, 4, zero_phase=True)
+                    + 1j * butterworth_lowpass(mixed.imag, sampling_rate, cutoff, 4, zero_phase=True))
+        offset = _symbol_start_offset(recover_symbol_timing(baseband, samples_per_symbol), samples_per_symbol)
+
+    num_symbols = (len(qam_signal) - offset) // samples_per_symbol
+    if num_symbols == 0:
+        return np.zeros(0, dtype=complex)
+
+    n = num_symbols * samples_per_symbol
+    t = (offset + np.arange(n)) / sampling_rate
+    r = qam_signal[offset:offset + n].reshape(num_symbols, samples_per_symbol)
+    c = np.cos(2.0 * np.pi * carrier_freq * t).reshape(num_symbols, samples_per_symbol)
+    s = -np.sin(2.0 * np.pi * carrier_freq * t).reshape(num_symbols, samples_per_symbol)
+
+    # Per-symbol 2x2 normal equations
+    cc = np.sum(c * c, axis=1)
+    ss = np.sum(s * s, axis=1)
+    cs = np.sum(c * s, axis=1)
+    rc = np.sum(r * c, axis=1)
+    rs = np.sum(r * s, axis=1)
+    det = cc * ss - cs * cs
+    return ((ss * rc - cs * rs) + 1j * (cc * rs - cs * rc)) / (det * carrier_amplitude)
+
+
+def qam_demodulate(qam_signal: np.ndarray, sampling_rate: float, symbol_rate: float,
+                   carrier_freq: float, order: int, carrier_amplitude: float = 1.0,
+                   timing_recovery: bool = False, iq_imbalance: IQImbalance | None = None,
+                   correct_imbalance: bool = False) -> np.ndarray:
+    """
+    Coherent QAM demodulation with nearest-point decisions.
+
+    The qam_soft_symbols estimates optionally pass through an I/Q imbalance
+    model and then correct_iq_imbalance, and each is replaced by the nearest
+    point of the constellation.
+
+    Args:
+        qam_signal: QAM modulated signal
+        sampling_rate: Sampling rate in Hz
+        symbol_rate: Symbol rate in symbols/s
+        carrier_freq: Carrier frequency (assumed phase-synchronous)
+        order: Constellation size, one of QAM_ORDERS
+        carrier_amplitude: Expected carrier amplitude
+        timing_recovery: See qam_soft_symbols
+        iq_imbalance: Receiver I/Q mismatch to simulate
+        correct_imbalance: Orthogonalize I and Q before deciding
+
+    Returns:
+        Decided constellation points (complex), one per whole symbol
+    """
+    from signals import qam_constellation
+
+    constellation = qam_constellation(order)
+    soft = qam_soft_symbols(qam_signal, sampling_rate, symbol_rate, carrier_freq,
+                            carrier_amplitude, timing_recovery)
+    if len(soft) == 0:
+        return soft
+    if iq_imbalance is not None:
+        soft = iq_imbalance.apply(soft)
+    if correct_imbalance:
+        soft = correct_iq_imbalance(soft)
+
+    nearest = np.argmin(np.abs(soft[:, None] - constellation[None, :]), axis=1)
+    return constellation[nearest]
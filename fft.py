@@ -0,0 +1,217 @@
+from __future__ import annotations
+
+import csv
+from typing import Tuple
+
+import numpy as np
+
+from windows import apply_window, generate_window
+
+
+def next_pow2(n: int) -> int:
+    """Smallest power of two >= n (1 for n <= 1)."""
+    if n <= 1:
+        return 1
+    return 1 << (int(n) - 1).bit_length()
+
+
+def _bit_reversed_indices(n: int) -> np.ndarray:
+    bits = n.bit_length() - 1
+    idx = np.arange(n)
+    rev = np.zeros(n, dtype=int)
+    for b in range(bits):
+        rev |= ((idx >> b) & 1) << (bits - 1 - b)
+    return rev
+
+
+def fft(values: np.ndarray) -> np.ndarray:
+    """
+    Iterative radix-2 Cooley-Tukey FFT.
+
+    Inputs whose length is not a power of two are zero-padded to next_pow2(len).
+
+    Args:
+        values: Real or complex input samples
+
+    Returns:
+        Complex spectrum of length next_pow2(len(values))
+    """
+    x = np.asarray(values, dtype=complex)
+    if len(x) == 0:
+        return np.zeros(0, dtype=complex)
+    n = next_pow2(len(x))
+    if n != len(x):
+        x = np.concatenate([x, np.zeros(n - len(x), dtype=complex)])
+
+    x = x[_bit_reversed_indices(n)]
+
+    # Butterfly stages: each row holds one block whose halves are sub-FFTs
+    size = 2
+    while size <= n:
+        half = size // 2
+        twiddle = np.exp(-2j * np.pi * np.arange(half) / size)
+        blocks = x.reshape(-1, size)
+        even = blocks[:, :half]
+        odd = blocks[:, half:] * twiddle
+        x = np.concatenate([even + odd, even - odd], axis=1).reshape(-1)
+        size *= 2
+
+    return x
+
+
+def ifft(spectrum: np.ndarray) -> np.ndarray:
+    """
+    Inverse of fft() returning the real part of the time-domain signal.
+
+    Args:
+        spectrum: Complex spectrum whose length is a power of two
+
+    Returns:
+        Real time-domain samples
+    """
+    X = np.asarray(spectrum, dtype=complex)
+    n = len(X)
+    if n == 0:
+        return np.zeros(0)
+    if n != next_pow2(n):
+        raise ValueError("Spectrum length must be a power of two")
+    return (np.conj(fft(np.conj(X))) / n).real
+
+
+def _periodograms(x: np.ndarray, sampling_rate: float, segment_length: int, overlap: int,
+                  window: str, zero_pad: int) -> Tuple[np.ndarray, np.ndarray, np.ndarray]:
+    # (segment start indices, bin frequencies, one-sided PSD per segment in 1/Hz)
+    x = np.asarray(x, dtype=float)
+    if segment_length < 2:
+        raise ValueError("Segment length must be at least 2")
+    if not (0 <= overlap < segment_length):
+        raise ValueError("Overlap must be in [0, segment_length)")
+    if len(x) < segment_length:
+        raise ValueError("Signal is shorter than one segment")
+    if zero_pad < 1:
+        raise ValueError("Zero-padding factor must be at least 1")
+
+    w = generate_window(window, segment_length)
+    step = segment_length - overlap
+    n_segments = 1 + (len(x) - segment_length) // step
+    nfft = next_pow2(segment_length * zero_pad)
+
+    starts = np.arange(n_segments) * step
+    psd = np.zeros((n_segments, nfft // 2 + 1))
+    for k, start in enumerate(starts):
+        segment = x[start:start + segment_length]
+        spectrum = fft(apply_window(segment - np.mean(segment), w))[:nfft // 2 + 1]
+        psd[k] = np.abs(spectrum) ** 2
+    psd /= sampling_rate * np.sum(w ** 2)
+
+    # Fold negative frequencies into the one-sided estimate
+    psd[:, 1:-1] *= 2.0
+
+    freqs = np.arange(nfft // 2 + 1) * sampling_rate / nfft
+    return starts, freqs, psd
+
+
+def welch_psd(x: np.ndarray, sampling_rate: float, segment_length: int = 256, overlap: int = 128,
+              window: str = "hann", zero_pad: int = 1) -> Tuple[np.ndarray, np.ndarray]:
+    """
+    One-sided power spectral density estimate using Welch's method.
+
+    The signal is split into overlapping segments; each is windowed and
+    transformed with fft(), and the periodograms are averaged.
+
+    Args:
+        x: Real input signal
+        sampling_rate: Sampling rate in Hz
+        segment_length: Samples per segment (zero-padded to a power of two)
+        overlap: Samples shared by consecutive segments
+        window: One of windows.WINDOW_TYPES
+        zero_pad: Transform length as a multiple of segment_length (before
+            rounding up to a power of two); finer bins, same resolution
+
+    Returns:
+        (freqs, power_db): bin frequencies in Hz and PSD in dB re 1/Hz
+    """
+    _, freqs, psd = _periodograms(x, sampling_rate, segment_length, overlap, window, zero_pad)
+    return freqs, 10.0 * np.log10(np.mean(psd, axis=0) + 1e-20)
+
+
+def spectrogram(x: np.ndarray, sampling_rate: float, segment_length: int = 256, overlap: int = 128,
+                window: str = "hann", zero_pad: int = 1) -> Tuple[np.ndarray, np.ndarray, np.ndarray]:
+    """
+    Short-time PSD: the Welch segments' periodograms without the averaging.
+
+    Arguments are as for welch_psd, whose estimate is the mean of these
+    slices in linear power.
+
+    Returns:
+        (times, freqs, power_db): segment centre times in s, bin frequencies
+        in Hz and a (len(times), len(freqs)) PSD array in dB re 1/Hz
+    """
+    starts, freqs, psd = _periodograms(x, sampling_rate, segment_length, overlap, window, zero_pad)
+    times = (starts + 0.5 * segment_length) / sampling_rate
+    return times, freqs, 10.0 * np.log10(psd + 1e-20)
+
+
+def occupied_bandwidth(x: np.ndarray, sampling_rate: float, fraction: float = 0.99,
+                       segment_length: int = 1024, window: str = "hann", zero_pad: int = 1) -> float:
+    """
+    Width in Hz of the band holding the given fraction of the signal power.
+
+    The Welch PSD is integrated and (1 - fraction) / 2 of the power is cut
+    from each end of the spectrum, as in the usual 99% occupied-bandwidth
+    measurement. The segment window keeps leakage from a strong tone out of
+    the tails; "rectangular" widens the estimate for off-bin tones. The step
+    is one bin, sampling_rate / next_pow2(segment_length * zero_pad).
+    """
+    if not (0.0 < fraction < 1.0):
+        raise ValueError("Power fraction must be in (0, 1)")
+    segment_length = min(segment_length, len(x))
+    freqs, power_db = welch_psd(x, sampling_rate, segment_length, segment_length // 2, window, zero_pad)
+    cumulative = np.cumsum(10.0 ** (power_db / 10.0))
+    cumulative /= cumulative[-1]
+    tail = 0.5 * (1.0 - fraction)
+    low = freqs[int(np.searchsorted(cumulative, tail))]
+    high = freqs[int(np.searchsorted(cumulative, 1.0 - tail))]
+    return float(high - low)
+
+
+def sideband_powers(x: np.ndarray, sampling_rate: float, carrier_freq: float,
+                    message_freq: float) -> Tuple[float, float, float]:
+    """
+    Split a tone-modulated signal's power into carrier, upper and lower sideband.
+
+    The Welch PSD (Hann window, bins no wider than message_freq / 8) is
+    integrated over three bands of width message_freq centered on
+    carrier_freq and carrier_freq +/- message_freq. AM shows all three with
+    equal sidebands, DSB-SC only the sidebands and SSB a single sideband.
+
+    Returns:
+        (carrier, upper, lower) powers in the units of x squared
+    """
+    if not (0.0 < message_freq < carrier_freq and carrier_freq + 1.5 * message_freq < 0.5 * sampling_rate):
+        raise ValueError("Carrier and both sidebands must lie between 0 Hz and Nyquist")
+    segment_length = min(len(x), next_pow2(int(np.ceil(8.0 * sampling_rate / message_freq))))
+    freqs, power_db = welch_psd(x, sampling_rate, segment_length, segment_length // 2)
+    power = 10.0 ** (power_db / 10.0) * (freqs[1] - freqs[0])
+
+    def band(center: float) -> float:
+        return float(np.sum(power[np.abs(freqs - center) < 0.5 * message_freq]))
+
+    return band(carrier_freq), band(carrier_freq + message_freq), band(carrier_freq - message_freq)
+
+
+def save_spectrum_csv(freqs: np.ndarray, power_db: np.ndarray, filename: str) -> None:
+    """
+    Write a spectrum, e.g. from welch_psd, as Frequency_Hz and Power_dB columns.
+
+    Raises:
+        ValueError: If freqs and power_db differ in length
+    """
+    freqs = np.asarray(freqs, dtype=float)
+    power_db = np.asarray(power_db, dtype=float)
+    if freqs.shape != power_db.shape:
+        raise ValueError(f"Spectrum has {freqs.size} frequencies but {power_db.size} power values")
+    with open(filename, 'w', newline='') as f:
+        writer = csv.writer(f)
+        writer.writerow(['Frequency_Hz', 'Power_dB'])
+        writer.writerows(np.column_stack([freqs, power_db]).tolist())
@@ -0,0 +1,247 @@
+from __future__ import annotations
+
+from dataclasses import dataclass
+from fractions import Fraction
+from typing import Tuple
+
+import numpy as np
+from scipy import signal
+
+from windows import WINDOW_TYPES, generate_window
+
+FIR_WINDOWS = WINDOW_TYPES
+
+
+def butterworth_sos(cutoff_hz: float, sampling_rate: float, order: int = 4,
+                    highpass: bool = False) -> np.ndarray:
+    """
+    Design a digital Butterworth low-pass (or high-pass) as second-order sections.
+
+    Each conjugate analog pole pair (Q_k = 1 / (2 sin((2k-1)π / 2N))) is mapped
+    to a biquad with the pre-warped bilinear transform, K = tan(π fc / fs). An
+    odd order adds one first-order section. The high-pass shares the poles
+    and moves the numerator zeros from z = -1 to z = 1.
+
+    Returns:
+        Array of shape (n_sections, 6) laid out as [b0, b1, b2, 1, a1, a2]
+    """
+    if order < 1:
+        raise ValueError("Filter order must be at least 1")
+    if not (0.0 < cutoff_hz < 0.5 * sampling_rate):
+        raise ValueError("Cutoff must be between 0 and the Nyquist frequency")
+
+    k = np.tan(np.pi * cutoff_hz / sampling_rate)
+    sections = []
+    for idx in range(1, order // 2 + 1):
+        q = 1.0 / (2.0 * np.sin((2 * idx - 1) * np.pi / (2 * order)))
+        norm = 1.0 / (1.0 + k / q + k * k)
+        a = [1.0, 2.0 * (k * k - 1.0) * norm, (1.0 - k / q + k * k) * norm]
+        if highpass:
+            sections.append([norm, -2.0 * norm, norm] + a)
+        else:
+            b0 = k * k * norm
+            sections.append([b0, 2.0 * b0, b0] + a)
+    if order % 2 == 1:
+        norm = 1.0 / (1.0 + k)
+        b = [norm, -norm] if highpass else [k * norm, k * norm]
+        sections.append(b + [0.0, 1.0, (k - 1.0) * norm, 0.0])
+    return np.array(sections)
+
+
+def butterworth_lowpass(x: np.ndarray, sampling_rate: float, cutoff_hz: float, order: int = 4,
+                        zero_phase: bool = False) -> np.ndarray:
+    """
+    Butterworth low-pass filter applied as a cascade of biquads.
+
+    Args:
+        x: Input signal
+        sampling_rate: Sampling rate in Hz
+        cutoff_hz: -3 dB cutoff frequency in Hz
+        order: Filter order
+        zero_phase: Run forward and backward (squared magnitude, no delay)
+
+    Returns:
+        Filtered signal
+    """
+    sos = butterworth_sos(cutoff_hz, sampling_rate, order)
+    if zero_phase:
+        return signal.sosfiltfilt(sos, x)
+    return signal.sosfilt(sos, x)
+
+
+def butterworth_bandpass(x: np.ndarray, sampling_rate: float, low_hz: float, high_hz: float,
+                         order: int = 4, zero_phase: bool = False) -> np.ndarray:
+    """
+    Band-pass filter built from a Butterworth high-pass at low_hz cascaded
+    with a Butterworth low-pass at high_hz, each of the given order.
+
+    Args:
+        x: Input signal
+        sampling_rate: Sampling rate in Hz
+        low_hz: Lower -3 dB edge in Hz
+        high_hz: Upper -3 dB edge in Hz
+        order: Order of each of the two sections
+        zero_phase: Run forward and backward (squared magnitude, no delay)
+
+    Returns:
+        Filtered signal
+    """
+    if not low_hz < high_hz:
+        raise ValueError("Lower band edge must be below the upper band edge")
+    sos = np.vstack([butterworth_sos(low_hz, sampling_rate, order, highpass=True),
+                     butterworth_sos(high_hz, sampling_rate, order)])
+    if zero_phase:
+        return signal.sosfiltfilt(sos, x)
+    return signal.sosfilt(sos, x)
+
+
+def rc_lowpass(x: np.ndarray, sampling_rate: float, rc_time_constant: float) -> np.ndarray:
+    """
+    Single-pole RC low-pass: y[n] = y[n-1] + alpha * (x[n] - y[n-1]).
+
+    alpha = dt / (RC + dt) with dt = 1 / sampling_rate, starting from an
+    uncharged capacitor (y[-1] = 0). As the smoothing stage of an envelope
+    detector, an RC much longer than the message period cannot follow the
+    envelope (diagonal clipping); one much shorter than the carrier period
+    leaves carrier ripple.
+    """
+    if not rc_time_constant > 0:
+        raise ValueError(f"RC time constant must be positive, got {rc_time_constant}")
+    dt = 1.0 / sampling_rate
+    alpha = dt / (rc_time_constant + dt)
+    return signal.lfilter([alpha], [1.0, alpha - 1.0], np.asarray(x, dtype=float))
+
+
+def dc_blocker(x: np.ndarray, pole: float = 0.995) -> np.ndarray:
+    """
+    Single-pole DC-blocking high-pass: y[n] = x[n] - x[n-1] + pole * y[n-1].
+
+    A streaming alternative to subtracting the mean: the zero at DC removes
+    a constant offset once the start-up transient (about 1 / (1 - pole)
+    samples) has decayed, and the -3 dB corner sits near
+    (1 - pole) * sampling_rate / (2 pi).
+    """
+    if not 0.0 < pole < 1.0:
+        raise ValueError(f"Pole must be in (0, 1), got {pole}")
+    return signal.lfilter([1.0, -1.0], [1.0, -pole], np.asarray(x, dtype=float))
+
+
+@dataclass
+class FIRFilter:
+    """Finite impulse response filter defined by its taps."""
+    taps: np.ndarray
+
+    @property
+    def group_delay(self) -> int:
+        """Delay in whole samples of a symmetric (linear-phase) design: (num_taps-1)/2, rounded down."""
+        return (len(self.taps) - 1) // 2
+
+    def apply(self, x: np.ndarray, compensate: bool = True) -> np.ndarray:
+        """Filter x; with compensate, drop the group delay so the output aligns with the input."""
+        x = np.asarray(x, dtype=float)
+        start = self.group_delay if compensate else 0
+        return np.convolve(x, self.taps)[start:start + len(x)]
+
+
+def design_fir_lowpass(cutoff_hz: float, sampling_rate: float, num_taps: int = 101,
+                       window: str = "hamming") -> FIRFilter:
+    """
+    Windowed-sinc FIR low-pass design.
+
+    Taps are a sinc centered on (num_taps-1)/2, shaped by the chosen window
+    and normalized to unity DC gain.
+
+    Args:
+        cutoff_hz: Cutoff frequency in Hz
+        sampling_rate: Sampling rate in Hz
+        num_taps: Number of taps (odd keeps the filter exactly centered)
+        window: One of FIR_WINDOWS
+
+    Returns:
+        FIRFilter with the designed taps
+    """
+    if num_taps < 1:
+        raise ValueError("Number of taps must be positive")
+    if not (0.0 < cutoff_hz < 0.5 * sampling_rate):
+        raise ValueError("Cutoff must be between 0 and the Nyquist frequency")
+
+    fc = cutoff_hz / sampling_rate
+    n = np.arange(num_taps) - (num_taps - 1) / 2.0
+    taps = 2.0 * fc * np.sinc(2.0 * fc * n) * generate_window(window, num_taps)
+    return FIRFilter(taps=taps / np.sum(taps))
+
+
+def decimate(x: np.ndarray, t: np.ndarray, factor: int, num_taps: int | None = None) -> Tuple[np.ndarray, np.ndarray]:
+    """
+    Reduce the sampling rate by an integer factor.
+
+    An anti-aliasing FIR low-pass at 90% of the new Nyquist frequency is
+    applied before keeping every factor-th sample.
+
+    Returns:
+        (t_new, x_new) with t_new spaced at the new sampling interval
+    """
+    if factor < 1:
+        raise ValueError("Decimation factor must be >= 1")
+    x = np.asarray(x, dtype=float)
+    if factor == 1:
+        return np.array(t, dtype=float), x.copy()
+    fs = 1.0 / float(np.mean(np.diff(t)))
+    taps = num_taps if num_taps is not None else 20 * factor + 1
+    fir = design_fir_lowpass(0.9 * 0.5 * fs / factor, fs, taps)
+    decimated = fir.apply(x)[::factor]
+    t_new = t[0] + np.arange(len(decimated)) * factor / fs
+    return t_new, decimated
+
+
+def interpolate(x: np.ndarray, t: np.ndarray, factor: int, num_taps: int | None = None) -> Tuple[np.ndarray, np.ndarray]:
+    """
+    Increase the sampling rate by an integer factor.
+
+    Samples are zero-stuffed and a reconstruction FIR low-pass at 90% of the
+    original Nyquist frequency (with gain = factor) fills in the gaps.
+
+    Returns:
+        (t_new, x_new) with t_new spaced at the new sampling interval
+    """
+    if factor < 1:
+        raise ValueError("Interpolation factor must be >= 1")
+    x = np.asarray(x, dtype=float)
+    if factor == 1:
+        return np.array(t, dtype=float), x.copy()
+    fs = 1.0 / float(np.mean(np.diff(t)))
+    fs_new = fs * factor
+    stuffed = np.zeros(len(x) * factor)
+    stuffed[::factor] = x
+    taps = num_taps if num_taps is not None else 20 * factor + 1
+    fir = design_fir_lowpass(0.9 * 0.5 * fs, fs_new, taps)
+    interpolated = factor * fir.apply(stuffed)
+    t_new = t[0] + np.arange(len(interpolated)) / fs_new
+    return t_new, interpolated
+
+
+def resample(x: np.ndarray, t: np.ndarray, new_rate: float,
+             max_denominator: int = 1000) -> Tuple[np.ndarray, np.ndarray]:
+    """
+    Convert to an arbitrary sampling rate with a polyphase filter.
+
+    new_rate / fs is approximated by a ratio up/down with down at most
+    max_denominator (exact for the usual audio and simulation rates, e.g.
+    44100 -> 100000 is 1000/441) and scipy's Kaiser-windowed polyphase
+    resampler does the conversion.
+
+    Returns:
+        (t_new, x_new) with t_new spaced at the achieved rate fs * up / down
+    """
+    if not new_rate > 0:
+        raise ValueError(f"New sampling rate must be positive, got {new_rate}")
+    x = np.asarray(x, dtype=float)
+    fs = 1.0 / float(np.mean(np.diff(t)))
+    ratio = Fraction(new_rate / fs).limit_denominator(max_denominator)
+    if ratio.numerator == 0:
+        raise ValueError(f"New sampling rate {new_rate} Hz is too low for {fs} Hz input")
+    if ratio == 1:
+        return np.array(t, dtype=float), x.copy()
+    resampled = signal.resample_poly(x, ratio.numerator, ratio.denominator)
+    t_new = t[0] + np.arange(len(resampled)) / (fs * ratio)
+    return t_new, resampled
@@ -3,22 +3,55 @@ from __future__ import annotations
 import argparse
 import os
 import sys
+from typing import Dict, Tuple
 
-from config import parse_args_and_get_params, print_summary
-from signals import generate_time_vector, message_signal, carrier_signal, am_modulate, fm_modulate
+import numpy as np
+
+from config import SimulationParams, parse_args_and_get_params, print_summary
+from signals import generate_time_vector, message_signal, carrier_signal, am_modulate, fm_modulate, save_signals_csv
+from fft import welch_psd, save_spectrum_csv
 from utils import run_monte_carlo_simulation, save_results_csv, save_results_json, print_performance_summary
-from plots import generate_all_plots, plot_snr_comparison, plot_signal_evolution, plot_noise_effects
+from utils import run_fsk_ber_simulation, benchmark_trials, benchmark_carrier_generation, save_detailed_measurements_csv, compare_to_baseline
+from utils import sweep_modulation_index, snr_index_heatmap
+from plots import generate_all_plots, plot_snr_comparison, plot_signal_evolution, plot_noise_effects, plot_ber_vs_ebn0
+from plots import SAVE_FORMATS, PLOT_THEMES, set_save_format, set_plot_theme, plot_modulation_index_sweep, plot_heatmap
+
+
+def export_waveforms(params: SimulationParams) -> Tuple[np.ndarray, Dict[str, np.ndarray]]:
+    """Time base and named waveforms of one AM/FM pass at the lowest SNR level."""
+    from modulation import modulate, demodulate
+    from noise import add_noise
+    t = generate_time_vector(params.sampling_rate, params.duration)
+    m = message_signal(t, params.message_freq, params.message_amplitude)
+    snr_db = min(params.snr_values) if params.snr_values else params.snr_min
+    waveforms = {"message": m, "carrier": carrier_signal(t, params.carrier_freq, params.carrier_amplitude)}
+    for mod_type in ("am", "fm"):
+        modulated = modulate(mod_type, m, t, params)
+        noisy = add_noise(modulated, snr_db, params.noise_distribution, seed=params.seed)
+        waveforms[mod_type] = modulated
+        waveforms[f"{mod_type}_noisy"] = noisy
+        waveforms[f"{mod_type}_demod"] = demodulate(mod_type, noisy, t, params)
+    return t, waveforms
 
 
 def main() -> None:
     parser = argparse.ArgumentParser(description="AM/FM Monte Carlo Simulation")
     parser.add_argument("--run-simulation", action="store_true", help="Run full Monte Carlo simulation")
+    parser.add_argument("--run-fsk", action="store_true", help="Run binary FSK bit-error-rate sweep")
+    parser.add_argument("--sweep-index", action="store_true", help="Sweep AM and FM modulation index at the highest SNR level and over all SNR levels")
+    parser.add_argument("--benchmark", action="store_true", help="Time Monte Carlo trials with and without shared clean signals, and carrier generation methods")
     parser.add_argument("--plot-signals", action="store_true", help="Generate signal evolution plots")
     parser.add_argument("--plot-noise", action="store_true", help="Generate noise effects plots")
+    parser.add_argument("--export-signals", action="store_true", help="Save message, carrier, AM/FM, noisy and demodulated waveforms to one CSV")
+    parser.add_argument("--export-spectrum", action="store_true", help="Save Welch PSDs of the noisy AM and FM signals to CSV")
     parser.add_argument("--plot-all", action="store_true", help="Generate all visualization plots")
+    parser.add_argument("--plot-format", choices=SAVE_FORMATS, default="png", help="Save plots as PNG, SVG or both")
+    parser.add_argument("--plot-theme", choices=PLOT_THEMES, default="default", help="Plot colors: default or dark (for slides)")
     parser.add_argument("--output-dir", type=str, default="outputs", help="Output directory for results and plots")
     parser.add_argument("--output-csv", type=str, default="monte_carlo_results.csv", help="CSV output filename")
     parser.add_argument("--output-json", type=str, default="monte_carlo_results.json", help="JSON output filename")
+    parser.add_argument("--baseline", type=str, help="Results CSV to compare the simulation against; exits non-zero on drift")
+    parser.add_argument("--baseline-tol", type=float, default=0.5, help="Allowed drift from --baseline (dB)")
     parser.add_argument("--mode", choices=["default", "interactive", "cli"], default="default", 
                        help="Execution mode: default (smoke test), interactive (prompts), cli (arguments)")
     
@@ -26,6 +59,8 @@ def main() -> None:
     
     # Create output directory
     os.makedirs(args.output_dir, exist_ok=True)
+    set_save_format(args.plot_format)
+    set_plot_theme(args.plot_theme)
     
     # Parse simulation parameters from remaining args
     sys.argv = ['main.py'] + remaining_args
@@ -44,9 +79,65 @@ def main() -> None:
         save_results_csv(results, csv_path)
         save_results_json(results, json_path)
         print(f"\nResults saved to {csv_path} and {json_path}")
+        if params.save_detailed:
+            detailed_path = os.path.join(args.output_dir, "monte_carlo_trials.csv")
+            save_detailed_measurements_csv(results, detailed_path)
+            print(f"Per-trial measurements saved to {detailed_path}")
         
         # Print summary
         print_performance_summary(results)
+        
+        if args.baseline:
+            try:
+                compare_to_baseline(results, args.baseline, args.baseline_tol)
+            except ValueError as exc:
+                print(f"\n{exc}")
+                sys.exit(1)
+            print(f"\nResults match baseline {args.baseline} within {args.baseline_tol:g} dB")
+    
+    if args.run_fsk:
+        print("\nRunning FSK BER simulation...")
+        ber_results = run_fsk_ber_simulation(params)
+        for snr in ber_results.snr_levels:
+            print(f"SNR {snr:5.1f} dB  Eb/N0 {ber_results.ebn0_db[snr]:5.1f} dB  BER {ber_results.ber[snr]:.2e}")
+        plot_ber_vs_ebn0(ber_results, os.path.join(args.output_dir, "fsk_ber.png"))
+    
+    if args.sweep_index:
+        print("\nSweeping modulation index...")
+        snr_db = max(params.snr_values) if params.snr_values else params.snr_max
+        snr_levels = sorted(params.snr_values) or [round(float(snr), 1) for snr in
+                                                    np.arange(params.snr_min, params.snr_max + params.snr_step, params.snr_step)]
+        for mod_type, indices in (("am", [0.2, 0.4, 0.6, 0.8, 1.0, 1.2, 1.5, 2.0]),
+                                  ("fm", [0.5, 1.0, 2.0, 3.0, 5.0, 8.0])):
+            sweep = sweep_modulation_index(mod_type, params, indices, snr_db, params.trials)
+            for index, mean in zip(sweep.indices, sweep.means):
+                print(f"{mod_type.upper()} index {index:4.1f}  output SNR {mean:6.2f} dB")
+            plot_modulation_index_sweep(sweep, os.path.join(args.output_dir, f"{mod_type}_index_sweep.png"))
+            plot_heatmap(snr_index_heatmap(mod_type, params, snr_levels, indices, params.trials), snr_levels, indices,
+                         os.path.join(args.output_dir, f"{mod_type}_index_heatmap.png"),
+                         y_label='Modulation Index' + (' ka' if mod_type == "am" else ' beta'),
+                         title=f'{mod_type.upper()} Output SNR (dB)')
+    
+    if args.benchmark:
+        print("\nBenchmarking Monte Carlo trials...")
+        fresh_rate, shared_rate = benchmark_trials(params)
+        print(f"Per-trial generation: {fresh_rate:.1f} trials/s")
+        print(f"Shared clean signals: {shared_rate:.1f} trials/s ({shared_rate / fresh_rate:.2f}x)")
+        direct_rate, recurrence_rate = benchmark_carrier_generation(params)
+        print(f"Carrier by np.sin: {direct_rate:.1f} carriers/s")
+        print(f"Carrier by rotation recurrence: {recurrence_rate:.1f} carriers/s ({recurrence_rate / direct_rate:.2f}x)")
+    
+    if args.export_signals:
+        signals_path = os.path.join(args.output_dir, "signals.csv")
+        save_signals_csv(*export_waveforms(params), signals_path)
+        print(f"\nWaveforms saved to {signals_path}")
+    
+    if args.export_spectrum:
+        _, waveforms = export_waveforms(params)
+        for mod_type in ("am", "fm"):
+            spectrum_path = os.path.join(args.output_dir, f"{mod_type}_spectrum.csv")
+            save_spectrum_csv(*welch_psd(waveforms[f"{mod_type}_noisy"], params.sampling_rate), spectrum_path)
+            print(f"{mod_type.upper()} spectrum saved to {spectrum_path}")
     
     if args.plot_all:
         print("\nGenerating all visualization plots...")
@@ -63,7 +154,8 @@ def main() -> None:
         if results is not None:
             plot_snr_comparison(results, os.path.join(args.output_dir, "snr_comparison.png"))
     
-    if not any([args.run_simulation, args.plot_signals, args.plot_noise, args.plot_all]):
+    if not any([args.run_simulation, args.run_fsk, args.sweep_index, args.benchmark, args.export_signals,
+                args.export_spectrum, args.plot_signals, args.plot_noise, args.plot_all]):
         # Quick smoke test for generation and modulation (no I/O side effects)
         print("\nRunning smoke test...")
         t = generate_time_vector(params.sampling_rate, params.duration)
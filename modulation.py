@@ -0,0 +1,190 @@
+from __future__ import annotations
+
+from typing import Callable, Dict, Tuple
+
+import numpy as np
+
+from config import SimulationParams
+
+Modulator = Callable[[np.ndarray, np.ndarray, SimulationParams], np.ndarray]  # (message, t, params) -> signal
+Demodulator = Callable[[np.ndarray, np.ndarray, SimulationParams], np.ndarray]  # (received, t, params) -> message
+
+# Built-in analog schemes, registered below
+MODULATION_TYPES = ("am", "fm", "dsbsc", "pm")
+
+_REGISTRY: Dict[str, Tuple[Modulator, Demodulator]] = {}
+
+
+def register_modulation(name: str, modulator: Modulator, demodulator: Demodulator) -> None:
+    """
+    Register a modulation scheme for modulate/demodulate and the Monte Carlo trials.
+
+    Registering an existing name replaces it. Schemes registered at run time
+    are visible to parallel workers only when they are forked, not spawned.
+    """
+    if not name:
+        raise ValueError("Modulation name must be non-empty")
+    if not callable(modulator) or not callable(demodulator):
+        raise TypeError(f"Modulator and demodulator for {name!r} must be callable")
+    _REGISTRY[name] = (modulator, demodulator)
+
+
+def registered_modulations() -> Tuple[str, ...]:
+    """Names of all registered schemes, built-ins first."""
+    return tuple(_REGISTRY)
+
+
+def _lookup(mod_type: str) -> Tuple[Modulator, Demodulator]:
+    try:
+        return _REGISTRY[mod_type]
+    except KeyError:
+        raise ValueError(f"Unknown modulation type {mod_type!r}, "
+                         f"expected one of {', '.join(_REGISTRY)}") from None
+
+
+def modulate(mod_type: str, message: np.ndarray, t: np.ndarray, params: SimulationParams) -> np.ndarray:
+    """
+    Modulate a message with a registered scheme using the simulation parameters.
+
+    Built-in FM applies pre-emphasis when params.fm_emphasis_tau is set;
+    demodulate undoes it, so the pair is transparent to callers. Built-in
+    AM scales its carrier term by params.am_variant and, for a full
+    carrier, clips a negative envelope to zero when
+    params.clamp_overmodulation is set.
+
+    Raises:
+        ValueError: If mod_type is not registered
+    """
+    return _lookup(mod_type)[0](message, t, params)
+
+
+def demodulate(mod_type: str, received: np.ndarray, t: np.ndarray, params: SimulationParams) -> np.ndarray:
+    """
+    Recover the message from a received signal of a registered scheme.
+
+    Built-in AM and FM use the receivers selected by params.am_demodulator
+    (with the params.am_diode rectifier for "envelope"; coherent whenever
+    params.am_variant drops the full carrier, see config.am_receiver) and
+    params.fm_demodulator, with the post-detection low-pass at
+    params.demod_cutoff_hz (2x the message frequency when 0). FM passes
+    through a band-pass limiter first when params.fm_limiter is set.
+
+    Raises:
+        ValueError: If mod_type is not registered
+    """
+    return _lookup(mod_type)[1](received, t, params)
+
+
+def demodulate_and_decimate(mod_type: str, received: np.ndarray, t: np.ndarray, params: SimulationParams,
+                            output_rate: float) -> Tuple[np.ndarray, np.ndarray]:
+    """
+    Demodulate, then low-pass and decimate the output to about output_rate.
+
+    The decimation factor is the largest integer keeping the rate at or
+    above output_rate; the anti-aliasing filter sits at 90% of the new
+    Nyquist frequency, so noise above the message band is dropped along
+    with the samples.
+
+    Returns:
+        (t_new, message) at params.sampling_rate / factor
+
+    Raises:
+        ValueError: If mod_type is not registered or output_rate does not
+            cover the message (<= 2x message_freq) or exceeds the sampling rate
+    """
+    if not (2.0 * params.message_freq < output_rate <= params.sampling_rate):
+        raise ValueError(f"Output rate {output_rate} Hz must be above twice the message frequency "
+                         f"and at most the sampling rate {params.sampling_rate} Hz")
+    from filters import decimate
+    factor = int(params.sampling_rate // output_rate)
+    return decimate(demodulate(mod_type, received, t, params), t, factor)
+
+
+def _demod_cutoff(params: SimulationParams) -> float:
+    return params.demod_cutoff_hz or 2.0 * params.message_freq
+
+
+def _am_modulate(message: np.ndarray, t: np.ndarray, params: SimulationParams) -> np.ndarray:
+    from signals import am_modulate, am_modulate_clamped, am_carrier_level
+    if params.am_variant != "full-carrier":
+        return am_modulate(message, t, params.carrier_freq, params.carrier_amplitude, params.am_index,
+                           am_carrier_level(params.am_variant, params.am_pilot_level))
+    if params.clamp_overmodulation:
+        return am_modulate_clamped(message, t, params.carrier_freq, params.carrier_amplitude, params.am_index)[0]
+    return am_modulate(message, t, params.carrier_freq, params.carrier_amplitude, params.am_index)
+
+
+def _am_demodulate(received: np.ndarray, t: np.ndarray, params: SimulationParams) -> np.ndarray:
+    from config import am_receiver
+    from demod import am_demodulate_envelope, am_demodulate_hilbert, am_demodulate_coherent, dsbsc_demodulate_costas
+    if params.am_variant == "suppressed-carrier":
+        # No carrier for a PLL to lock to; the Costas loop recovers it from the sidebands
+        return dsbsc_demodulate_costas(received, t, params.carrier_freq, params.carrier_amplitude,
+                                       cutoff_hz=_demod_cutoff(params))
+    receiver = am_receiver(params)
+    if receiver == "hilbert":
+        return am_demodulate_hilbert(received, t, params.carrier_freq,
+                                     params.carrier_amplitude, cutoff_hz=_demod_cutoff(params))
+    if receiver == "coherent":
+        return am_demodulate_coherent(received, t, params.carrier_freq,
+                                      params.carrier_amplitude, cutoff_hz=_demod_cutoff(params))
+    return am_demodulate_envelope(received, t, params.carrier_freq,
+                                  params.carrier_amplitude, cutoff_hz=_demod_cutoff(params), diode=params.am_diode)
+
+
+def _fm_modulate(message: np.ndarray, t: np.ndarray, params: SimulationParams) -> np.ndarray:
+    from signals import fm_modulate, pre_emphasis
+    if params.fm_emphasis_tau > 0:
+        message = pre_emphasis(message, params.sampling_rate, params.fm_emphasis_tau)
+    return fm_modulate(message, t, params.carrier_freq, params.carrier_amplitude,
+                       params.fm_deviation, params.sampling_rate, params.fm_integration)
+
+
+def _fm_limit(received: np.ndarray, params: SimulationParams) -> np.ndarray:
+    from demod import hard_limiter
+    from utils import carrier_bandpass, carson_bandwidth
+    # Band-pass limiter: clip to a near square wave, then keep only the
+    # fundamental so the discriminator sees a constant-envelope carrier
+    limited = hard_limiter(received, 0.01 * params.carrier_amplitude)
+    return carrier_bandpass(limited, params, carson_bandwidth(params))
+
+
+def _fm_demodulate(received: np.ndarray, t: np.ndarray, params: SimulationParams) -> np.ndarray:
+    from demod import fm_demodulate_instantaneous_frequency, fm_demodulate_pll, de_emphasis
+    if params.fm_limiter:
+        received = _fm_limit(received, params)
+    if params.fm_demodulator == "pll":
+        demodulated = fm_demodulate_pll(received, t, params.carrier_freq, params.fm_deviation,
+                                        params.pll_loop_bandwidth or None)
+    else:
+        demodulated = fm_demodulate_instantaneous_frequency(received, t, params.carrier_freq,
+                                                            params.fm_deviation, cutoff_hz=_demod_cutoff(params))
+    if params.fm_emphasis_tau > 0:
+        demodulated = de_emphasis(demodulated, params.sampling_rate, params.fm_emphasis_tau)
+    return demodulated
+
+
+def _dsbsc_modulate(message: np.ndarray, t: np.ndarray, params: SimulationParams) -> np.ndarray:
+    from signals import dsbsc_modulate
+    return dsbsc_modulate(message, t, params.carrier_freq, params.carrier_amplitude)
+
+
+def _dsbsc_demodulate(received: np.ndarray, t: np.ndarray, params: SimulationParams) -> np.ndarray:
+    from demod import dsbsc_demodulate_costas
+    return dsbsc_demodulate_costas(received, t, params.carrier_freq, params.carrier_amplitude)
+
+
+def _pm_modulate(message: np.ndarray, t: np.ndarray, params: SimulationParams) -> np.ndarray:
+    from signals import pm_modulate
+    return pm_modulate(message, t, params.carrier_freq, params.carrier_amplitude, params.pm_index)
+
+
+def _pm_demodulate(received: np.ndarray, t: np.ndarray, params: SimulationParams) -> np.ndarray:
+    from demod import pm_demodulate
+    return pm_demodulate(received, t, params.carrier_freq, params.pm_index)
+
+
+register_modulation("am", _am_modulate, _am_demodulate)
+register_modulation("fm", _fm_modulate, _fm_demodulate)
+register_modulation("dsbsc", _dsbsc_modulate, _dsbsc_demodulate)
+register_modulation("pm", _pm_modulate, _pm_demodulate)
@@ -1,6 +1,11 @@
 from __future__ import annotations
 
+from dataclasses import dataclass
+
 import numpy as np
+from scipy import signal as sps
+
+NOISE_DISTRIBUTIONS = ("gaussian", "uniform", "laplacian")
 
 
 def add_gaussian_noise(signal: np.ndarray, snr_db: float, seed: int | None = None) -> np.ndarray:
@@ -15,8 +20,9 @@ def add_gaussian_noise(signal: np.ndarray, snr_db: float, seed: int | None = Non
     Returns:
         Noisy signal with the specified SNR
     """
-    if seed is not None:
-        np.random.seed(seed)
+    # A private generator leaves the global NumPy state alone; RandomState
+    # draws the same stream np.random.seed(seed) used to
+    rng = np.random.RandomState(seed)
     
     # Convert SNR from dB to linear scale
     snr_linear = 10.0 ** (snr_db / 10.0)
@@ -29,7 +35,7 @@ def add_gaussian_noise(signal: np.ndarray, snr_db: float, seed: int | None = Non
     
     # Generate Gaussian noise with the required power
     noise_std = np.sqrt(noise_power)
-    noise = np.random.normal(0, noise_std, size=signal.shape)
+    noise = rng.normal(0, noise_std, size=signal.shape)
     
     # Add noise to signal
     noisy_signal = signal + noise
@@ -37,6 +43,260 @@ def add_gaussian_noise(signal: np.ndarray, snr_db: float, seed: int | None = Non
     return noisy_signal
 
 
+def add_noise(signal: np.ndarray, snr_db: float, distribution: str = "gaussian",
+              seed: int | None = None) -> np.ndarray:
+    """
+    Add zero-mean white noise from one of NOISE_DISTRIBUTIONS at the desired SNR.
+    
+    Each distribution is scaled through its own variance so the noise power
+    is signal_power / SNR: Gaussian std sqrt(P), uniform on [-a, a] with
+    a = sqrt(3P) (variance a^2/3), Laplacian with scale b = sqrt(P/2)
+    (variance 2b^2). Gaussian noise is exactly add_gaussian_noise.
+    
+    Args:
+        signal: Input signal array
+        snr_db: Desired signal-to-noise ratio in dB
+        distribution: One of NOISE_DISTRIBUTIONS
+        seed: Random seed for reproducibility (optional)
+    
+    Returns:
+        Noisy signal with the specified SNR
+    """
+    if distribution == "gaussian":
+        return add_gaussian_noise(signal, snr_db, seed)
+    noise_power = np.mean(signal ** 2) / (10.0 ** (snr_db / 10.0))
+    rng = np.random.default_rng(seed)
+    if distribution == "uniform":
+        half_width = np.sqrt(3.0 * noise_power)
+        return signal + rng.uniform(-half_width, half_width, size=signal.shape)
+    if distribution == "laplacian":
+        return signal + rng.laplace(0.0, np.sqrt(noise_power / 2.0), size=signal.shape)
+    raise ValueError(f"Unknown noise distribution {distribution!r}, expected one of {', '.join(NOISE_DISTRIBUTIONS)}")
+
+
+def ebn0_to_snr_db(ebn0_db: float, bit_rate: float, sampling_rate: float) -> float:
+    """
+    Per-sample SNR that gives the requested Eb/N0 for a real sampled signal.
+    
+    Eb = P / Rb. White noise of variance σ² is assumed to fill the whole
+    simulated band 0..fs/2, so its one-sided density is N0 = σ² / (fs/2).
+    Together: SNR = P / σ² = (Eb/N0) * 2 Rb / fs.
+    """
+    if bit_rate <= 0 or sampling_rate <= 0:
+        raise ValueError("Bit rate and sampling rate must be positive")
+    return float(ebn0_db + 10.0 * np.log10(2.0 * bit_rate / sampling_rate))
+
+
+def add_awgn_ebn0(signal: np.ndarray, ebn0_db: float, bit_rate: float, sampling_rate: float,
+                  seed: int | None = None) -> np.ndarray:
+    """
+    Add white Gaussian noise specified by Eb/N0 instead of per-sample SNR.
+    
+    The conversion (see ebn0_to_snr_db) uses the signal's average power as
+    the energy per bit times the bit rate, and the full fs/2 noise bandwidth.
+    
+    Args:
+        signal: Input signal array
+        ebn0_db: Desired energy-per-bit to noise density ratio in dB
+        bit_rate: Bit rate in bit/s
+        sampling_rate: Sampling rate in Hz
+        seed: Random seed for reproducibility (optional)
+    
+    Returns:
+        Noisy signal
+    """
+    return add_gaussian_noise(signal, ebn0_to_snr_db(ebn0_db, bit_rate, sampling_rate), seed)
+
+
+def add_colored_noise(signal: np.ndarray, snr_db: float, exponent: float = 1.0,
+                      seed: int | None = None) -> np.ndarray:
+    """
+    Add power-law (1/f^exponent) Gaussian noise to a signal at the desired SNR.
+    
+    White noise is shaped in the frequency domain by 1/f^(exponent/2), so its
+    PSD falls as 1/f^exponent: 0 is white, 1 is pink, 2 is brown. The DC bin
+    is zeroed and the result is scaled to hit snr_db exactly.
+    
+    Args:
+        signal: Input signal array
+        snr_db: Desired signal-to-noise ratio in dB
+        exponent: PSD slope exponent
+        seed: Random seed for reproducibility (optional)
+    
+    Returns:
+        Noisy signal with the specified SNR
+    """
+    rng = np.random.RandomState(seed)
+    
+    n = signal.size
+    spectrum = np.fft.rfft(rng.normal(0.0, 1.0, size=n))
+    freqs = np.fft.rfftfreq(n)
+    
+    shaping = np.zeros_like(freqs)
+    shaping[1:] = freqs[1:] ** (-exponent / 2.0)
+    noise = np.fft.irfft(spectrum * shaping, n=n).reshape(signal.shape)
+    
+    # Scale to the exact noise power the SNR calls for
+    noise_power = np.mean(signal ** 2) / (10.0 ** (snr_db / 10.0))
+    noise *= np.sqrt(noise_power / np.mean(noise ** 2))
+    
+    return signal + noise
+
+
+def add_impulse_noise(signal: np.ndarray, probability: float, amplitude: float,
+                      seed: int | None = None) -> np.ndarray:
+    """
+    Add impulsive (click) noise: each sample independently receives a
+    spike of +amplitude or -amplitude with the given probability.
+    
+    Args:
+        signal: Input signal array
+        probability: Per-sample impulse probability in [0, 1]
+        amplitude: Spike magnitude
+        seed: Random seed for reproducibility (optional)
+    
+    Returns:
+        Signal with impulses added
+    """
+    if not (0.0 <= probability <= 1.0):
+        raise ValueError(f"Impulse probability must be in [0, 1], got {probability}")
+    
+    rng = np.random.default_rng(seed)
+    hits = rng.random(signal.shape) < probability
+    signs = rng.choice([-1.0, 1.0], size=signal.shape)
+    
+    return signal + amplitude * signs * hits
+
+
+def apply_frequency_offset(x: np.ndarray, offset_hz: float, sampling_rate: float) -> np.ndarray:
+    """
+    Shift a real passband signal by offset_hz, as a mistuned oscillator would.
+    
+    The analytic signal is multiplied by exp(j 2π offset t) and the real
+    part kept, so every spectral component moves by the same offset.
+    """
+    t = np.arange(len(x)) / sampling_rate
+    return np.real(sps.hilbert(x) * np.exp(2j * np.pi * offset_hz * t))
+
+
+def phase_noise(num_samples: int, rms_rad: float, sampling_rate: float, bandwidth_hz: float = 100.0,
+                seed: int | None = None) -> np.ndarray:
+    """
+    Zero-mean oscillator phase jitter with the given RMS in radians.
+
+    White Gaussian noise is passed through a one-pole lowpass with a
+    bandwidth_hz corner, giving a Lorentzian-shaped phase spectrum, and is
+    then scaled so the sample RMS is exactly rms_rad.
+    """
+    if rms_rad < 0:
+        raise ValueError(f"Phase noise RMS must be non-negative, got {rms_rad}")
+    if not bandwidth_hz > 0:
+        raise ValueError(f"Phase noise bandwidth must be positive, got {bandwidth_hz}")
+    rng = np.random.default_rng(seed)
+    alpha = 1.0 - np.exp(-2.0 * np.pi * bandwidth_hz / sampling_rate)
+    phi = sps.lfilter([alpha], [1.0, alpha - 1.0], rng.normal(0.0, 1.0, num_samples))
+    phi -= np.mean(phi)
+    rms = np.sqrt(np.mean(phi ** 2))
+    if rms == 0:
+        return phi
+    return phi * (rms_rad / rms)
+
+
+def add_phase_noise(x: np.ndarray, rms_rad: float, sampling_rate: float, bandwidth_hz: float = 100.0,
+                    seed: int | None = None) -> np.ndarray:
+    """
+    Perturb the carrier phase of a real passband signal by phase_noise:
+    Re{exp(jφ(t)) * analytic(x)}. The envelope is untouched, so the
+    impairment shows up in coherent and angle demodulation but not in an
+    envelope detector.
+    """
+    phi = phase_noise(len(x), rms_rad, sampling_rate, bandwidth_hz, seed)
+    return np.real(sps.hilbert(x) * np.exp(1j * phi))
+
+
+def add_timing_jitter(x: np.ndarray, jitter_std_s: float, sampling_rate: float,
+                      seed: int | None = None) -> np.ndarray:
+    """
+    Resample a signal at sampling instants perturbed by independent Gaussian
+    jitter of jitter_std_s seconds, using linear interpolation between the
+    original samples. The error grows with slope, so roughly 2π f σ for a
+    tone at f.
+    """
+    if jitter_std_s < 0:
+        raise ValueError(f"Jitter standard deviation must be non-negative, got {jitter_std_s}")
+    rng = np.random.default_rng(seed)
+    t = np.arange(len(x)) / sampling_rate
+    jittered = np.clip(t + rng.normal(0.0, jitter_std_s, len(x)), t[0], t[-1])
+    return np.interp(jittered, t, x)
+
+
+def quantize(signal: np.ndarray, bits: int, full_scale: float) -> np.ndarray:
+    """
+    Model an ideal mid-rise ADC with 2**bits levels spanning +/-full_scale.
+    
+    Levels are spaced 2*full_scale / 2**bits apart with none at zero;
+    samples beyond the range saturate to the outermost level. A full-scale
+    sine then comes out at about 6.02*bits + 1.76 dB SNR.
+    """
+    if bits < 1:
+        raise ValueError(f"ADC resolution must be at least 1 bit, got {bits}")
+    if not full_scale > 0:
+        raise ValueError(f"Full scale must be positive, got {full_scale}")
+    levels = 2 ** bits
+    step = 2.0 * full_scale / levels
+    codes = np.clip(np.floor((np.asarray(signal, dtype=float) + full_scale) / step), 0, levels - 1)
+    return -full_scale + (codes + 0.5) * step
+
+
+def add_interferer(signal: np.ndarray, interferer: np.ndarray, relative_power_db: float) -> np.ndarray:
+    """
+    Add a second signal scaled to relative_power_db above (or, if negative,
+    below) the power of the first.
+    """
+    if len(signal) != len(interferer):
+        raise ValueError(f"Interferer has {len(interferer)} samples, signal has {len(signal)}")
+    interferer_power = np.mean(interferer ** 2)
+    if interferer_power == 0:
+        return signal.copy()
+    target_power = np.mean(signal ** 2) * 10.0 ** (relative_power_db / 10.0)
+    return signal + interferer * np.sqrt(target_power / interferer_power)
+
+
+@dataclass
+class RayleighChannel:
+    """
+    Flat Rayleigh fading channel (Clarke sum-of-sinusoids model).
+    
+    The complex gain h(t) = (1/sqrt(M)) Σ exp(j(2π f_d cos(α_n) t + φ_n)) sums M
+    scattered paths with random arrival angles α_n and phases φ_n, so |h| is
+    approximately Rayleigh with E[|h|^2] = 1 and a Doppler spread of f_d.
+    """
+    doppler_hz: float
+    sampling_rate: float
+    seed: int | None = None
+    num_paths: int = 32
+    
+    def __post_init__(self):
+        if self.doppler_hz < 0:
+            raise ValueError(f"Doppler frequency must be non-negative, got {self.doppler_hz}")
+        if self.num_paths < 1:
+            raise ValueError(f"Number of paths must be positive, got {self.num_paths}")
+        rng = np.random.default_rng(self.seed)
+        self._angles = rng.uniform(-np.pi, np.pi, self.num_paths)
+        self._phases = rng.uniform(-np.pi, np.pi, self.num_paths)
+    
+    def gains(self, num_samples: int) -> np.ndarray:
+        """Complex fading gain for the first num_samples samples."""
+        t = np.arange(num_samples) / self.sampling_rate
+        doppler = self.doppler_hz * np.cos(self._angles)
+        paths = np.exp(1j * (2.0 * np.pi * np.outer(t, doppler) + self._phases))
+        return paths.sum(axis=1) / np.sqrt(self.num_paths)
+    
+    def apply(self, x: np.ndarray) -> np.ndarray:
+        """Fade a real passband signal: Re{h(t) * analytic(x)}."""
+        return np.real(self.gains(len(x)) * sps.hilbert(x))
+
+
 def calculate_signal_power(signal: np.ndarray) -> float:
     """Calculate the average power of a signal."""
     return float(np.mean(signal ** 2))
@@ -1,12 +1,125 @@
 from __future__ import annotations
 
 import os
+from dataclasses import dataclass
 import matplotlib.pyplot as plt
 import numpy as np
-from typing import Dict, List, Optional
+from typing import Dict, List, Optional, Tuple
 
 from config import SimulationParams
-from utils import PerformanceResults
+from utils import BERResults, PerformanceResults, SNRMeasurement, theoretical_am_output_snr_db, theoretical_fm_output_snr_db
+from utils import carson_bandwidth, eye_opening, CaptureEffectResults, run_capture_effect
+from utils import IndexSweepResults
+
+# "png" writes save_path as given; "svg" and "both" swap in or add a .svg twin
+SAVE_FORMATS = ("png", "svg", "both")
+_save_format = "png"
+
+
+def set_save_format(save_format: str) -> None:
+    """Select the file format(s) every plot function saves; one of SAVE_FORMATS."""
+    global _save_format
+    if save_format not in SAVE_FORMATS:
+        raise ValueError(f"Unknown save format {save_format!r}, expected one of {', '.join(SAVE_FORMATS)}")
+    _save_format = save_format
+
+
+@dataclass(frozen=True)
+class PlotTheme:
+    """Colors and font shared by every plot; AM/FM comparisons draw in am_color/fm_color."""
+    am_color: str
+    fm_color: str
+    grid_color: str
+    background: str
+    foreground: str  # text, axes, ticks and reference lines
+    font_family: str = "sans-serif"
+
+
+DEFAULT_THEME = PlotTheme(am_color="tab:blue", fm_color="tab:orange", grid_color="#b0b0b0",
+                          background="white", foreground="black")
+DARK_THEME = PlotTheme(am_color="#4fc3f7", fm_color="#ffb74d", grid_color="#555555",
+                       background="#1e1e1e", foreground="#e0e0e0")
+PLOT_THEMES = {"default": DEFAULT_THEME, "dark": DARK_THEME}
+_theme = DEFAULT_THEME
+
+
+def set_plot_theme(theme: PlotTheme | str) -> None:
+    """Select the theme (a PlotTheme or a PLOT_THEMES name) for every plot drawn afterwards."""
+    global _theme
+    if isinstance(theme, str):
+        if theme not in PLOT_THEMES:
+            raise ValueError(f"Unknown plot theme {theme!r}, expected one of {', '.join(PLOT_THEMES)}")
+        theme = PLOT_THEMES[theme]
+    _theme = theme
+    plt.rcParams.update({
+        'figure.facecolor': theme.background,
+        'axes.facecolor': theme.background,
+        'savefig.facecolor': theme.background,
+        'legend.facecolor': theme.background,
+        'axes.edgecolor': theme.foreground,
+        'axes.labelcolor': theme.foreground,
+        'axes.titlecolor': theme.foreground,
+        'text.color': theme.foreground,
+        'xtick.color': theme.foreground,
+        'ytick.color': theme.foreground,
+        'legend.labelcolor': theme.foreground,
+        'grid.color': theme.grid_color,
+        'font.family': theme.font_family,
+    })
+
+
+LEGEND_POSITIONS = ("best", "upper right", "upper left", "lower left", "lower right", "outside")
+
+
+@dataclass(frozen=True)
+class PlotLayout:
+    """Axis limits (NaN = auto-scale) and legend placement for one set of axes."""
+    x_min: float = float('nan')
+    x_max: float = float('nan')
+    y_min: float = float('nan')
+    y_max: float = float('nan')
+    legend: str = "best"  # one of LEGEND_POSITIONS; "outside" puts it right of the axes
+
+
+def apply_layout(ax, layout: Optional[PlotLayout]) -> None:
+    """Apply a PlotLayout's explicit limits and legend position to ax; None leaves ax as drawn."""
+    if layout is None:
+        return
+    if layout.legend not in LEGEND_POSITIONS:
+        raise ValueError(f"Unknown legend position {layout.legend!r}, expected one of {', '.join(LEGEND_POSITIONS)}")
+    # set_*lim(None) keeps the auto-scaled end
+    ax.set_xlim(None if np.isnan(layout.x_min) else layout.x_min, None if np.isnan(layout.x_max) else layout.x_max)
+    ax.set_ylim(None if np.isnan(layout.y_min) else layout.y_min, None if np.isnan(layout.y_max) else layout.y_max)
+    if ax.get_legend() is not None:
+        if layout.legend == "outside":
+            ax.legend(loc='upper left', bbox_to_anchor=(1.02, 1.0))
+        else:
+            ax.legend(loc=layout.legend)
+
+
+def _save_figure(save_path: str, dpi: int = 300) -> List[str]:
+    """Save the current figure in the selected format(s); returns the paths written."""
+    base, ext = os.path.splitext(save_path)
+    if _save_format == "png":
+        paths = [save_path]
+    elif _save_format == "svg":
+        paths = [base + ".svg"]
+    else:
+        paths = [base + (ext if ext and ext != ".svg" else ".png"), base + ".svg"]
+    for path in paths:
+        plt.savefig(path, dpi=dpi, bbox_inches='tight')
+    return paths
+
+
+def render_figure(fig=None, dpi: int = 80) -> np.ndarray:
+    """
+    Draw a figure (the current one by default) in memory and return its
+    pixels as an RGBA uint8 array, for comparing plots without files.
+    """
+    fig = fig if fig is not None else plt.gcf()
+    fig.set_dpi(dpi)
+    fig.canvas.draw()
+    return np.asarray(fig.canvas.buffer_rgba()).copy()
 
 
 def plot_baseband_and_carrier(params: SimulationParams, save_path: Optional[str] = None) -> None:
@@ -37,7 +150,7 @@ def plot_baseband_and_carrier(params: SimulationParams, save_path: Optional[str]
     
     plt.tight_layout()
     if save_path:
-        plt.savefig(save_path, dpi=300, bbox_inches='tight')
+        _save_figure(save_path)
     plt.show()
 
 
@@ -57,13 +170,13 @@ def plot_modulated_signals(params: SimulationParams, save_path: Optional[str] =
     # Show first 0.01 seconds or 1000 samples, whichever is smaller
     max_samples = min(1000, int(0.01 * params.sampling_rate))
     
-    ax1.plot(t[:max_samples], am_signal[:max_samples], 'g-', linewidth=2, label='AM Modulated')
+    ax1.plot(t[:max_samples], am_signal[:max_samples], color=_theme.am_color, linewidth=2, label='AM Modulated')
     ax1.set_title('AM Modulated Signal')
     ax1.set_ylabel('Amplitude')
     ax1.legend()
     ax1.grid(True, alpha=0.3)
     
-    ax2.plot(t[:max_samples], fm_signal[:max_samples], 'm-', linewidth=2, label='FM Modulated')
+    ax2.plot(t[:max_samples], fm_signal[:max_samples], color=_theme.fm_color, linewidth=2, label='FM Modulated')
     ax2.set_title('FM Modulated Signal')
     ax2.set_xlabel('Time (s)')
     ax2.set_ylabel('Amplitude')
@@ -72,7 +185,44 @@ def plot_modulated_signals(params: SimulationParams, save_path: Optional[str] =
     
     plt.tight_layout()
     if save_path:
-        plt.savefig(save_path, dpi=300, bbox_inches='tight')
+        _save_figure(save_path)
+    plt.show()
+
+
+def plot_spectral_comparison(params: SimulationParams, save_path: Optional[str] = None) -> None:
+    """Plot Welch PSD estimates of the AM and FM signals."""
+    from signals import generate_time_vector, message_signal, am_modulate, fm_modulate
+    from fft import welch_psd
+    
+    t = generate_time_vector(params.sampling_rate, params.duration)
+    message = message_signal(t, params.message_freq, params.message_amplitude)
+    
+    am_signal = am_modulate(message, t, params.carrier_freq, params.carrier_amplitude, params.am_index)
+    fm_signal = fm_modulate(message, t, params.carrier_freq, params.carrier_amplitude, 
+                           params.fm_deviation, params.sampling_rate)
+    
+    segment_length = min(1024, len(t))
+    freqs, am_psd = welch_psd(am_signal, params.sampling_rate, segment_length, segment_length // 2)
+    _, fm_psd = welch_psd(fm_signal, params.sampling_rate, segment_length, segment_length // 2)
+    
+    fig, ax = plt.subplots(figsize=(12, 6))
+    
+    ax.plot(freqs, am_psd, color=_theme.am_color, linewidth=1.5, label='AM')
+    ax.plot(freqs, fm_psd, color=_theme.fm_color, linewidth=1.5, alpha=0.8, label='FM')
+    ax.axvline(params.carrier_freq, color=_theme.foreground, linestyle='--', alpha=0.5, label='Carrier')
+    carson = carson_bandwidth(params)
+    ax.axvspan(params.carrier_freq - carson / 2, params.carrier_freq + carson / 2, color=_theme.fm_color, alpha=0.1,
+               label=f"FM Carson bandwidth ({carson:.0f} Hz)")
+    ax.set_title('Power Spectral Density (Welch)')
+    ax.set_xlabel('Frequency (Hz)')
+    ax.set_ylabel('PSD (dB/Hz)')
+    ax.set_ylim(max(am_psd.max(), fm_psd.max()) - 100, max(am_psd.max(), fm_psd.max()) + 10)
+    ax.legend()
+    ax.grid(True, alpha=0.3)
+    
+    plt.tight_layout()
+    if save_path:
+        _save_figure(save_path)
     plt.show()
 
 
@@ -127,7 +277,7 @@ def plot_noisy_vs_original(params: SimulationParams, snr_db: float = 10.0,
     
     plt.tight_layout()
     if save_path:
-        plt.savefig(save_path, dpi=300, bbox_inches='tight')
+        _save_figure(save_path)
     plt.show()
 
 
@@ -179,36 +329,427 @@ def plot_demodulated_vs_original(params: SimulationParams, snr_db: float = 10.0,
     
     plt.tight_layout()
     if save_path:
-        plt.savefig(save_path, dpi=300, bbox_inches='tight')
+        _save_figure(save_path)
     plt.show()
 
 
-def plot_snr_comparison(results: PerformanceResults, save_path: Optional[str] = None) -> None:
-    """Plot AM vs FM output SNR comparison."""
-    fig, ax = plt.subplots(figsize=(10, 6))
+def subsample(t: np.ndarray, x: np.ndarray, max_points: int) -> Tuple[np.ndarray, np.ndarray]:
+    """Keep every k-th sample, with the smallest k that leaves at most max_points."""
+    if max_points < 1:
+        raise ValueError("max_points must be at least 1")
+    step = max(1, -(-len(x) // max_points))
+    return t[::step], x[::step]
+
+
+def plot_signal_overlay(t: np.ndarray, signals: Dict[str, np.ndarray], save_path: Optional[str] = None,
+                        max_points: int = 2000, title: str = "Signal Overlay") -> None:
+    """
+    Overlay named signals on one time axis, e.g. clean, noisy and demodulated.
+
+    Each signal is subsampled to at most max_points vertices so long
+    recordings stay readable and the saved file stays small.
+    """
+    fig, ax = plt.subplots(figsize=(12, 5))
+    for name, x in signals.items():
+        if len(x) != len(t):
+            raise ValueError(f"Signal {name!r} has {len(x)} samples, time base has {len(t)}")
+        ax.plot(*subsample(t, x, max_points), linewidth=1, alpha=0.8, label=name)
+    ax.set_title(title)
+    ax.set_xlabel('Time (s)')
+    ax.set_ylabel('Amplitude')
+    ax.legend()
+    ax.grid(True, alpha=0.3)
     
+    plt.tight_layout()
+    if save_path:
+        _save_figure(save_path)
+    plt.show()
+
+
+def _draw_snr_comparison(ax, results: PerformanceResults) -> None:
     snr_levels = results.snr_levels
     am_means = [results.am_means[snr] for snr in snr_levels]
     fm_means = [results.fm_means[snr] for snr in snr_levels]
-    am_stds = [results.am_stds[snr] for snr in snr_levels]
-    fm_stds = [results.fm_stds[snr] for snr in snr_levels]
     
-    # Plot with error bars
-    ax.errorbar(snr_levels, am_means, yerr=am_stds, label='AM', marker='o', capsize=5)
-    ax.errorbar(snr_levels, fm_means, yerr=fm_stds, label='FM', marker='s', capsize=5)
+    # Error bars: 95% confidence interval of the mean when available, else +/-1 sigma
+    if results.am_ci95:
+        am_err = np.array([[mean - results.am_ci95[snr][0], results.am_ci95[snr][1] - mean]
+                           for snr, mean in zip(snr_levels, am_means)]).T
+        fm_err = np.array([[mean - results.fm_ci95[snr][0], results.fm_ci95[snr][1] - mean]
+                           for snr, mean in zip(snr_levels, fm_means)]).T
+        suffix = ' (95% CI)'
+    else:
+        am_err = [results.am_stds[snr] for snr in snr_levels]
+        fm_err = [results.fm_stds[snr] for snr in snr_levels]
+        suffix = ' (\u00b11\u03c3)'
+    
+    ax.errorbar(snr_levels, am_means, yerr=am_err, label='AM' + suffix, marker='o', capsize=5, color=_theme.am_color)
+    ax.errorbar(snr_levels, fm_means, yerr=fm_err, label='FM' + suffix, marker='s', capsize=5, color=_theme.fm_color)
     
     # Plot diagonal line for reference (ideal case)
-    ax.plot(snr_levels, snr_levels, 'k--', alpha=0.5, label='Ideal (1:1)')
+    ax.plot(snr_levels, snr_levels, '--', color=_theme.foreground, alpha=0.5, label='Ideal (1:1)')
+    
+    # Textbook curves; input SNR here is measured over the full fs/2 band
+    if results.params is not None:
+        p = results.params
+        snr_in = np.array(snr_levels, dtype=float)
+        bandwidth_ratio = 0.5 * p.sampling_rate / p.message_freq
+        ax.plot(snr_in, theoretical_am_output_snr_db(snr_in, p.am_index, bandwidth_ratio),
+                '--', color=_theme.am_color, alpha=0.7, label='AM theory')
+        ax.plot(snr_in, theoretical_fm_output_snr_db(snr_in, p.fm_deviation / p.message_freq, bandwidth_ratio),
+                '--', color=_theme.fm_color, alpha=0.7, label='FM theory')
     
     ax.set_xlabel('Input SNR (dB)')
     ax.set_ylabel('Output SNR (dB)')
     ax.set_title('AM vs FM Performance Comparison')
     ax.legend()
     ax.grid(True, alpha=0.3)
+
+
+def plot_snr_comparison(results: PerformanceResults, save_path: Optional[str] = None,
+                        layout: Optional[PlotLayout] = None) -> None:
+    """Plot AM vs FM output SNR comparison."""
+    fig, ax = plt.subplots(figsize=(10, 6))
+    
+    _draw_snr_comparison(ax, results)
+    apply_layout(ax, layout)
+    
+    plt.tight_layout()
+    if save_path:
+        _save_figure(save_path)
+    plt.show()
+
+
+def plot_performance_dashboard(results: PerformanceResults, save_path: Optional[str] = None,
+                               width: float = 16.0, height: float = 12.0, dpi: int = 150,
+                               snr_layout: Optional[PlotLayout] = None,
+                               advantage_layout: Optional[PlotLayout] = None) -> None:
+    """
+    Plot a 2x2 summary: SNR curves, FM advantage, std-dev bars and a text summary.
+    
+    snr_layout and advantage_layout fix the limits and legend of the first two
+    panels, e.g. to keep several dashboards on the same scale.
+    """
+    fig, axes = plt.subplots(2, 2, figsize=(width, height))
+    
+    snr_levels = np.array(results.snr_levels, dtype=float)
+    am_means = np.array([results.am_means[snr] for snr in results.snr_levels])
+    fm_means = np.array([results.fm_means[snr] for snr in results.snr_levels])
+    am_stds = np.array([results.am_stds[snr] for snr in results.snr_levels])
+    fm_stds = np.array([results.fm_stds[snr] for snr in results.snr_levels])
+    advantage = fm_means - am_means
+    
+    # Panel 1: output vs input SNR
+    _draw_snr_comparison(axes[0, 0], results)
+    
+    # Panel 2: FM advantage
+    axes[0, 1].plot(snr_levels, advantage, 'g-o', linewidth=2, label='FM - AM')
+    axes[0, 1].axhline(0.0, color=_theme.foreground, linestyle='--', alpha=0.5)
+    axes[0, 1].set_title('FM Advantage over AM')
+    axes[0, 1].set_xlabel('Input SNR (dB)')
+    axes[0, 1].set_ylabel('Output SNR difference (dB)')
+    axes[0, 1].legend()
+    axes[0, 1].grid(True, alpha=0.3)
+    if snr_layout is not None:
+        apply_layout(axes[0, 0], snr_layout)
+    if advantage_layout is not None:
+        apply_layout(axes[0, 1], advantage_layout)
+    
+    # Panel 3: spread of the Monte Carlo trials
+    bar_width = 0.4 * (snr_levels[1] - snr_levels[0]) if len(snr_levels) > 1 else 0.4
+    axes[1, 0].bar(snr_levels - bar_width / 2, am_stds, width=bar_width, color=_theme.am_color, label='AM')
+    axes[1, 0].bar(snr_levels + bar_width / 2, fm_stds, width=bar_width, color=_theme.fm_color, label='FM')
+    axes[1, 0].set_title('Output SNR Standard Deviation')
+    axes[1, 0].set_xlabel('Input SNR (dB)')
+    axes[1, 0].set_ylabel('Std (dB)')
+    axes[1, 0].legend()
+    axes[1, 0].grid(True, alpha=0.3)
+    
+    # Panel 4: text summary
+    best = int(np.argmax(advantage))
+    summary = (
+        f"SNR points: {len(snr_levels)} ({snr_levels.min():.1f} to {snr_levels.max():.1f} dB)\n"
+        f"Mean AM output SNR: {np.mean(am_means):.2f} dB\n"
+        f"Mean FM output SNR: {np.mean(fm_means):.2f} dB\n"
+        f"Mean FM advantage: {np.mean(advantage):.2f} dB\n"
+        f"Peak FM advantage: {advantage[best]:.2f} dB at {snr_levels[best]:.1f} dB input\n"
+        f"FM better at {int(np.sum(advantage > 0))}/{len(snr_levels)} SNR points"
+    )
+    axes[1, 1].axis('off')
+    axes[1, 1].set_title('Summary')
+    axes[1, 1].text(0.05, 0.95, summary, transform=axes[1, 1].transAxes, va='top',
+                    family='monospace', fontsize=12)
+    
+    plt.tight_layout()
+    if save_path:
+        _save_figure(save_path, dpi)
+    plt.show()
+
+
+def plot_snr_histogram(measurements: List[SNRMeasurement], snr_point: float, bins: int,
+                       save_path: Optional[str] = None, modulation: Optional[str] = None) -> np.ndarray:
+    """Histogram the per-trial output SNR at one input SNR; returns the bin counts."""
+    if bins < 1:
+        raise ValueError("bins must be positive")
+    values = np.array([m.output_snr_db for m in measurements
+                       if np.isclose(m.input_snr_db, snr_point)
+                       and (modulation is None or m.modulation == modulation)], dtype=float)
+    values = values[np.isfinite(values)]
+    if len(values) == 0:
+        raise ValueError(f"No measurements at {snr_point} dB input SNR")
+    
+    fig, ax = plt.subplots(figsize=(10, 6))
+    
+    counts, _, _ = ax.hist(values, bins=bins, edgecolor=_theme.foreground, alpha=0.7)
+    ax.axvline(np.mean(values), color='r', linestyle='--', label=f'Mean {np.mean(values):.2f} dB')
+    label = modulation if modulation is not None else 'All schemes'
+    ax.set_title(f'{label}: Output SNR Distribution at {snr_point:g} dB Input ({len(values)} trials)')
+    ax.set_xlabel('Output SNR (dB)')
+    ax.set_ylabel('Trials')
+    ax.legend()
+    ax.grid(True, alpha=0.3)
     
     plt.tight_layout()
     if save_path:
-        plt.savefig(save_path, dpi=300, bbox_inches='tight')
+        _save_figure(save_path)
+    plt.show()
+    return counts
+
+
+def plot_eye_diagram(x: np.ndarray, samples_per_symbol: int, save_path: Optional[str] = None) -> None:
+    """Overlay consecutive two-symbol windows of a baseband signal."""
+    if samples_per_symbol < 1:
+        raise ValueError("samples_per_symbol must be positive")
+    x = np.asarray(x, dtype=float)
+    # Drop any trailing partial symbol
+    x = x[:(len(x) // samples_per_symbol) * samples_per_symbol]
+    window = 2 * samples_per_symbol
+    if len(x) < window:
+        raise ValueError("Need at least two whole symbols")
+    
+    fig, ax = plt.subplots(figsize=(10, 6))
+    
+    offsets = np.arange(window) / samples_per_symbol
+    for start in range(0, len(x) - window + 1, samples_per_symbol):
+        ax.plot(offsets, x[start:start + window], 'b-', alpha=0.2, linewidth=0.8)
+    
+    ax.set_xlim(0, 2)
+    ax.set_title(f'Eye Diagram (opening {eye_opening(x, samples_per_symbol):.3f})')
+    ax.set_xlabel('Time (symbols)')
+    ax.set_ylabel('Amplitude')
+    ax.grid(True, alpha=0.3)
+    
+    plt.tight_layout()
+    if save_path:
+        _save_figure(save_path)
+    plt.show()
+
+
+def plot_constellation(i: np.ndarray, q: np.ndarray, save_path: Optional[str] = None,
+                       reference_points: Optional[np.ndarray] = None) -> None:
+    """Scatter I/Q decision samples on symmetric axes, with optional ideal points."""
+    i = np.asarray(i, dtype=float)
+    q = np.asarray(q, dtype=float)
+    if len(i) != len(q):
+        raise ValueError("I and Q must have the same length")
+    
+    fig, ax = plt.subplots(figsize=(7, 7))
+    
+    ax.scatter(i, q, s=8, alpha=0.5, label='Samples')
+    extent = max(np.max(np.abs(i)), np.max(np.abs(q))) if len(i) else 1.0
+    if reference_points is not None:
+        reference = np.asarray(reference_points, dtype=complex)
+        for point in reference:
+            ax.axvline(point.real, color='r', linestyle=':', alpha=0.4)
+            ax.axhline(point.imag, color='r', linestyle=':', alpha=0.4)
+        ax.scatter(reference.real, reference.imag, marker='x', s=80, color='r', label='Ideal')
+        extent = max(extent, np.max(np.abs(reference.real)), np.max(np.abs(reference.imag)))
+    
+    limit = 1.1 * extent if extent > 0 else 1.0
+    ax.set_xlim(-limit, limit)
+    ax.set_ylim(-limit, limit)
+    ax.set_aspect('equal')
+    ax.set_title('Constellation Diagram')
+    ax.set_xlabel('In-phase (I)')
+    ax.set_ylabel('Quadrature (Q)')
+    ax.legend()
+    ax.grid(True, alpha=0.3)
+    
+    plt.tight_layout()
+    if save_path:
+        _save_figure(save_path)
+    plt.show()
+
+
+def plot_modulation_index_sweep(sweep: IndexSweepResults, save_path: Optional[str] = None) -> None:
+    """Plot output SNR (mean +/- std) against modulation index at a fixed input SNR."""
+    fig, ax = plt.subplots(figsize=(10, 6))
+    
+    name = sweep.mod_type.upper()
+    ax.errorbar(sweep.indices, sweep.means, yerr=sweep.stds, fmt='o-', capsize=4,
+                color=_theme.am_color if sweep.mod_type == "am" else _theme.fm_color, label=name)
+    if sweep.mod_type == "am":
+        ax.axvline(1.0, color=_theme.foreground, linestyle='--', alpha=0.5, label='Overmodulation (ka = 1)')
+    ax.set_xlabel('Modulation Index' + (' ka' if sweep.mod_type == "am" else ' beta'))
+    ax.set_ylabel('Output SNR (dB)')
+    ax.set_title(f'{name} Output SNR vs Modulation Index at {sweep.input_snr_db:g} dB Input SNR')
+    ax.legend()
+    ax.grid(True, alpha=0.3)
+    
+    plt.tight_layout()
+    if save_path:
+        _save_figure(save_path)
+    plt.show()
+
+
+def plot_heatmap(matrix: np.ndarray, x_values: List[float], y_values: List[float],
+                 save_path: Optional[str] = None, x_label: str = 'Input SNR (dB)',
+                 y_label: str = 'Modulation Index', title: str = 'Output SNR (dB)') -> None:
+    """
+    Color map of matrix[i, j] at x_values[i], y_values[j], e.g. from
+    snr_index_heatmap; each cell is annotated with its value.
+    """
+    matrix = np.asarray(matrix, dtype=float)
+    if matrix.shape != (len(x_values), len(y_values)):
+        raise ValueError(f"Expected a {len(x_values)} x {len(y_values)} matrix, got {matrix.shape}")
+    fig, ax = plt.subplots(figsize=(10, 6))
+    
+    image = ax.imshow(matrix.T, origin='lower', aspect='auto', cmap='viridis')
+    fig.colorbar(image, ax=ax, label=title)
+    ax.set_xticks(range(len(x_values)))
+    ax.set_xticklabels([f'{x:g}' for x in x_values])
+    ax.set_yticks(range(len(y_values)))
+    ax.set_yticklabels([f'{y:g}' for y in y_values])
+    for i in range(len(x_values)):
+        for j in range(len(y_values)):
+            ax.text(i, j, f'{matrix[i, j]:.1f}', ha='center', va='center', color='w', fontsize=8)
+    ax.set_xlabel(x_label)
+    ax.set_ylabel(y_label)
+    ax.set_title(title)
+    
+    plt.tight_layout()
+    if save_path:
+        _save_figure(save_path)
+    plt.show()
+
+
+def plot_capture_effect(results: CaptureEffectResults, save_path: Optional[str] = None) -> None:
+    """Plot how strongly AM and FM outputs follow the wanted vs the interfering message."""
+    fig, ax = plt.subplots(figsize=(10, 6))
+    
+    ax.plot(results.advantage_db, results.fm_wanted, '-o', color=_theme.fm_color, label='FM: wanted message')
+    ax.plot(results.advantage_db, results.fm_interferer, '--s', color=_theme.fm_color, label='FM: interferer message')
+    ax.plot(results.advantage_db, results.am_wanted, '-o', color=_theme.am_color, label='AM: wanted message')
+    ax.plot(results.advantage_db, results.am_interferer, '--s', color=_theme.am_color, label='AM: interferer message')
+    ax.set_xlabel('Wanted-to-Interferer Power (dB)')
+    ax.set_ylabel('Correlation with Demodulated Output')
+    ax.set_title('Co-channel Interference: FM Capture Effect')
+    ax.legend()
+    ax.grid(True, alpha=0.3)
+    
+    plt.tight_layout()
+    if save_path:
+        _save_figure(save_path)
+    plt.show()
+
+
+def plot_waterfall(times: np.ndarray, freqs: np.ndarray, power_db: np.ndarray,
+                   save_path: Optional[str] = None, offset_db: float = 10.0,
+                   dynamic_range_db: float = 60.0) -> None:
+    """
+    Waterfall of a spectrogram (see fft.spectrogram): one PSD trace per time
+    slice, each raised offset_db above the previous and colored from early
+    (dark) to late (bright). Traces are clipped dynamic_range_db below the
+    overall peak so the noise floor does not swamp the stack.
+    """
+    power_db = np.asarray(power_db, dtype=float)
+    if power_db.shape != (len(times), len(freqs)):
+        raise ValueError(f"Expected a {len(times)} x {len(freqs)} spectrogram, got {power_db.shape}")
+    floor = np.max(power_db) - dynamic_range_db
+    colors = plt.cm.viridis(np.linspace(0.0, 1.0, len(times)))
+    
+    fig, ax = plt.subplots(figsize=(10, 8))
+    
+    for k, (trace, color) in enumerate(zip(power_db, colors)):
+        ax.plot(freqs, np.maximum(trace, floor) - floor + k * offset_db, color=color, linewidth=1)
+    sm = plt.cm.ScalarMappable(cmap='viridis', norm=plt.Normalize(times[0], times[-1]))
+    fig.colorbar(sm, ax=ax, label='Time (s)')
+    ax.set_xlabel('Frequency (Hz)')
+    ax.set_ylabel(f'PSD above floor (dB), traces offset {offset_db:g} dB')
+    ax.set_title('Spectrogram Waterfall')
+    ax.grid(True, alpha=0.3)
+    
+    plt.tight_layout()
+    if save_path:
+        _save_figure(save_path)
+    plt.show()
+
+
+def _plot_fm_waterfall(params: SimulationParams, save_path: str) -> None:
+    from signals import generate_time_vector, message_signal
+    from modulation import modulate
+    from fft import spectrogram
+    
+    t = generate_time_vector(params.sampling_rate, params.duration)
+    fm_signal = modulate("fm", message_signal(t, params.message_freq, params.message_amplitude), t, params)
+    # Segments a quarter message period long show the carrier swinging over two periods
+    segment_length = max(16, int(params.sampling_rate / (4.0 * params.message_freq)))
+    span = min(len(t), int(2.0 * params.sampling_rate / params.message_freq) + segment_length)
+    times, freqs, power_db = spectrogram(fm_signal[:span], params.sampling_rate, segment_length,
+                                         3 * segment_length // 4, zero_pad=4)
+    plot_waterfall(times, freqs, power_db, save_path)
+
+
+def _draw_ber(ax, x: List[float], ber: List[float], floor: float, label: str) -> None:
+    # Zero BER cannot be drawn on a log axis; clip it to the floor
+    ax.semilogy(x, np.maximum(np.asarray(ber, dtype=float), floor), marker='o', label=label)
+
+
+def plot_ber_curve(snr_db: List[float], ber: List[float], save_path: Optional[str] = None,
+                   floor: float = 1e-7, label: str = 'BER') -> None:
+    """
+    Plot bit error rate on a logarithmic axis against SNR in dB.
+
+    Points with zero BER are drawn at floor.
+    """
+    if len(snr_db) != len(ber):
+        raise ValueError("SNR and BER sequences must have the same length")
+    if not floor > 0:
+        raise ValueError("BER floor must be positive")
+    fig, ax = plt.subplots(figsize=(10, 6))
+    
+    _draw_ber(ax, snr_db, ber, floor, label)
+    ax.set_xlabel('SNR (dB)')
+    ax.set_ylabel('Bit Error Rate')
+    ax.set_title('BER vs SNR')
+    ax.legend()
+    ax.grid(True, which='both', alpha=0.3)
+    
+    plt.tight_layout()
+    if save_path:
+        _save_figure(save_path)
+    plt.show()
+
+
+def plot_ber_vs_ebn0(results: BERResults, save_path: Optional[str] = None) -> None:
+    """Plot FSK bit error rate against Eb/N0 on a logarithmic axis."""
+    fig, ax = plt.subplots(figsize=(10, 6))
+    
+    ebn0 = [results.ebn0_db[snr] for snr in results.snr_levels]
+    # Show zero BER at half an error per trial
+    floor = 0.5 / max(results.bits_per_trial, 1)
+    ber = [results.ber[snr] for snr in results.snr_levels]
+    
+    _draw_ber(ax, ebn0, ber, floor, f'BFSK ({results.bit_rate:g} bit/s)')
+    ax.set_xlabel('Eb/N0 (dB)')
+    ax.set_ylabel('Bit Error Rate')
+    ax.set_title('FSK BER vs Eb/N0')
+    ax.legend()
+    ax.grid(True, which='both', alpha=0.3)
+    
+    plt.tight_layout()
+    if save_path:
+        _save_figure(save_path)
     plt.show()
 
 
@@ -301,7 +842,7 @@ def plot_signal_evolution(params: SimulationParams, save_path: Optional[str] = N
     
     plt.tight_layout()
     if save_path:
-        plt.savefig(save_path, dpi=300, bbox_inches='tight')
+        _save_figure(save_path)
     plt.show()
 
 
@@ -338,19 +879,21 @@ def plot_noise_effects(params: SimulationParams, snr_levels: List[float] = [0, 5
     t = generate_time_vector(params.sampling_rate, params.duration)
     original_message = message_signal(t, params.message_freq, params.message_amplitude)
     
+    # Clean signals are the same at every SNR level; only the noise changes
+    am_signal = am_modulate(original_message, t, params.carrier_freq, 
+                           params.carrier_amplitude, params.am_index)
+    fm_signal = fm_modulate(original_message, t, params.carrier_freq, 
+                           params.carrier_amplitude, params.fm_deviation, params.sampling_rate)
+    
     fig, axes = plt.subplots(len(snr_levels), 2, figsize=(15, 3*len(snr_levels)))
     
     for i, snr_db in enumerate(snr_levels):
         # AM path
-        am_signal = am_modulate(original_message, t, params.carrier_freq, 
-                               params.carrier_amplitude, params.am_index)
         am_noisy = add_gaussian_noise(am_signal, snr_db, seed=42)
         am_demodulated = am_demodulate_envelope(am_noisy, t, params.carrier_freq, 
                                               params.carrier_amplitude)
         
         # FM path
-        fm_signal = fm_modulate(original_message, t, params.carrier_freq, 
-                               params.carrier_amplitude, params.fm_deviation, params.sampling_rate)
         fm_noisy = add_gaussian_noise(fm_signal, snr_db, seed=42)
         fm_demodulated = fm_demodulate_instantaneous_frequency(fm_noisy, t, params.carrier_freq, 
                                                               params.fm_deviation)
@@ -376,10 +919,23 @@ def plot_noise_effects(params: SimulationParams, snr_levels: List[float] = [0, 5
     
     plt.tight_layout()
     if save_path:
-        plt.savefig(save_path, dpi=300, bbox_inches='tight')
+        _save_figure(save_path)
     plt.show()
 
 
+def _plot_am_overlay(params: SimulationParams, snr_db: float, save_path: str) -> None:
+    from signals import generate_time_vector, message_signal
+    from modulation import modulate, demodulate
+    from noise import add_gaussian_noise
+    
+    t = generate_time_vector(params.sampling_rate, params.duration)
+    message = message_signal(t, params.message_freq, params.message_amplitude)
+    noisy = add_gaussian_noise(modulate("am", message, t, params), snr_db, seed=42)
+    plot_signal_overlay(t, {'Message': message, f'Noisy AM ({snr_db:g} dB)': noisy,
+                            'AM Demodulated': demodulate("am", noisy, t, params)},
+                        save_path, title=f'AM Clean / Noisy / Demodulated (SNR={snr_db:g}dB)')
+
+
 def generate_all_plots(params: SimulationParams, results: Optional[PerformanceResults] = None, 
                       output_dir: str = "outputs") -> None:
     """Generate all visualization plots and save to output directory."""
@@ -390,13 +946,19 @@ def generate_all_plots(params: SimulationParams, results: Optional[PerformanceRe
     # Basic signal plots
     plot_baseband_and_carrier(params, os.path.join(output_dir, "baseband_and_carrier.png"))
     plot_modulated_signals(params, os.path.join(output_dir, "modulated_signals.png"))
+    plot_spectral_comparison(params, os.path.join(output_dir, "spectral_comparison.png"))
     plot_noisy_vs_original(params, 10.0, os.path.join(output_dir, "noisy_vs_original.png"))
     plot_demodulated_vs_original(params, 10.0, os.path.join(output_dir, "demodulated_vs_original.png"))
     plot_signal_evolution(params, os.path.join(output_dir, "signal_evolution.png"))
     plot_noise_effects(params, save_path=os.path.join(output_dir, "noise_effects.png"))
+    _plot_am_overlay(params, 10.0, os.path.join(output_dir, "am_overlay.png"))
+    plot_capture_effect(run_capture_effect(params, [0.0, 2.0, 4.0, 6.0, 8.0, 10.0, 15.0, 20.0]),
+                        os.path.join(output_dir, "capture_effect.png"))
+    _plot_fm_waterfall(params, os.path.join(output_dir, "fm_waterfall.png"))
     
     # Performance comparison plot (if results available)
     if results is not None:
         plot_snr_comparison(results, os.path.join(output_dir, "snr_comparison.png"))
+        plot_performance_dashboard(results, os.path.join(output_dir, "performance_dashboard.png"))
     
     print(f"All plots saved to {output_dir}/")
@@ -13,6 +13,11 @@ from test_signals import TestSignalGeneration
 from test_noise import TestNoiseFunctions
 from test_demod import TestDemodulation
 from test_utils import TestUtilsFunctions
+from test_fft import TestFFT
+from test_filters import TestFilters
+from test_plots import TestPlots
+from test_modulation import TestModulation
+from test_windows import TestWindows
 
 
 def run_all_tests():
@@ -26,6 +31,11 @@ def run_all_tests():
     test_suite.addTest(unittest.TestLoader().loadTestsFromTestCase(TestNoiseFunctions))
     test_suite.addTest(unittest.TestLoader().loadTestsFromTestCase(TestDemodulation))
     test_suite.addTest(unittest.TestLoader().loadTestsFromTestCase(TestUtilsFunctions))
+    test_suite.addTest(unittest.TestLoader().loadTestsFromTestCase(TestFFT))
+    test_suite.addTest(unittest.TestLoader().loadTestsFromTestCase(TestFilters))
+    test_suite.addTest(unittest.TestLoader().loadTestsFromTestCase(TestPlots))
+    test_suite.addTest(unittest.TestLoader().loadTestsFromTestCase(TestModulation))
+    test_suite.addTest(unittest.TestLoader().loadTestsFromTestCase(TestWindows))
     
     # Run tests
     runner = unittest.TextTestRunner(verbosity=2)
@@ -54,5 +64,8 @@ def run_all_tests():
 
 
 if __name__ == '__main__':
+    # Rewrite the golden images that test_plots compares renders against
+    if '--update-golden' in sys.argv:
+        os.environ['UPDATE_GOLDEN'] = '1'
     success = run_all_tests()
     sys.exit(0 if success else 1)
@@ -1,9 +1,18 @@
 from __future__ import annotations
 
+import csv
+import wave
+from dataclasses import dataclass
+from typing import Callable, Dict, Sequence, Tuple
+
 import numpy as np
 
 
 def generate_time_vector(sampling_rate: float, duration: float) -> np.ndarray:
+    if not sampling_rate > 0:
+        raise ValueError(f"Sampling rate must be positive, got {sampling_rate}")
+    if not duration > 0:
+        raise ValueError(f"Duration must be positive, got {duration}")
     num_samples = int(np.round(sampling_rate * duration))
     if num_samples <= 0:
         raise ValueError("Number of samples must be positive")
@@ -15,17 +24,322 @@ def message_signal(t: np.ndarray, message_freq: float, amplitude: float = 1.0, p
     return amplitude * np.sin(2.0 * np.pi * message_freq * t + phase)
 
 
+def multitone_signal(t: np.ndarray, freqs: Sequence[float], amplitudes: Sequence[float]) -> np.ndarray:
+    # Sum of sines, e.g. a two-tone input for intermodulation tests; each tone must be below Nyquist
+    if len(freqs) != len(amplitudes):
+        raise ValueError(f"Got {len(freqs)} frequencies but {len(amplitudes)} amplitudes")
+    if len(freqs) == 0:
+        raise ValueError("Need at least one tone")
+    if len(t) > 1:
+        nyquist = 0.5 / float(t[1] - t[0])
+        for f in freqs:
+            if not 0.0 <= f < nyquist:
+                raise ValueError(f"Tone {f} Hz is outside [0, Nyquist = {nyquist} Hz)")
+    return mix_signals([message_signal(t, f) for f in freqs], amplitudes)
+
+
+def chirp_signal(t: np.ndarray, start_freq: float, end_freq: float, amplitude: float = 1.0) -> np.ndarray:
+    # Linear sweep: f(t) ramps from start_freq at t[0] to end_freq at t[-1],
+    # phase 2π (f0 τ + (f1 - f0) τ² / 2T) with τ = t - t[0], T = t[-1] - t[0]
+    tau = np.asarray(t, dtype=float) - t[0]
+    span = tau[-1] if len(tau) > 1 else 1.0
+    rate = (end_freq - start_freq) / span
+    return amplitude * np.sin(2.0 * np.pi * (start_freq * tau + 0.5 * rate * tau ** 2))
+
+
+def square_wave_signal(t: np.ndarray, freq: float, amplitude: float = 1.0, duty_cycle: float = 0.5) -> np.ndarray:
+    # +amplitude for the first duty_cycle of each period, -amplitude for the rest
+    if not 0.0 < duty_cycle < 1.0:
+        raise ValueError(f"Duty cycle must be in (0, 1), got {duty_cycle}")
+    fraction = np.mod(freq * np.asarray(t, dtype=float), 1.0)
+    return np.where(fraction < duty_cycle, amplitude, -amplitude)
+
+
+def arbitrary_signal(t: np.ndarray, fn: Callable[[np.ndarray], np.ndarray]) -> np.ndarray:
+    # Message from any function of time; fn gets the whole time vector and may be a plain ufunc expression
+    m = np.asarray(fn(np.asarray(t, dtype=float)), dtype=float)
+    if m.shape != np.shape(t):
+        m = np.broadcast_to(m, np.shape(t)).copy()
+    return m
+
+
+def _check_same_length(*signals: np.ndarray) -> None:
+    lengths = [len(x) for x in signals]
+    if len(set(lengths)) > 1:
+        raise ValueError(f"Signals must share a time base, got lengths {lengths}")
+
+
+def add_signals(a: np.ndarray, b: np.ndarray) -> np.ndarray:
+    # Sample-wise sum of two signals on the same time base
+    _check_same_length(a, b)
+    return np.asarray(a, dtype=float) + np.asarray(b, dtype=float)
+
+
+def multiply_signals(a: np.ndarray, b: np.ndarray) -> np.ndarray:
+    # Sample-wise product, e.g. a mixer or product detector
+    _check_same_length(a, b)
+    return np.asarray(a, dtype=float) * np.asarray(b, dtype=float)
+
+
+def scale_signal(x: np.ndarray, factor: float) -> np.ndarray:
+    return factor * np.asarray(x, dtype=float)
+
+
+def mix_signals(signals: Sequence[np.ndarray], weights: Sequence[float]) -> np.ndarray:
+    # Weighted sum of one or more signals on the same time base
+    if len(signals) != len(weights):
+        raise ValueError(f"Got {len(signals)} signals but {len(weights)} weights")
+    if len(signals) == 0:
+        raise ValueError("Need at least one signal")
+    _check_same_length(*signals)
+    return sum(scale_signal(x, w) for x, w in zip(signals, weights))
+
+
 def carrier_signal(t: np.ndarray, carrier_freq: float, amplitude: float = 1.0, phase: float = 0.0) -> np.ndarray:
     return amplitude * np.sin(2.0 * np.pi * carrier_freq * t + phase)
 
 
-def am_modulate(m: np.ndarray, t: np.ndarray, carrier_freq: float, carrier_amplitude: float = 1.0, am_index: float = 0.5) -> np.ndarray:
-    # s_AM(t) = Ac * (1 + ka*m(t)) * sin(2π f_c t)
-    return carrier_amplitude * (1.0 + am_index * m) * np.sin(2.0 * np.pi * carrier_freq * t)
+def rotation_sine(num_samples: int, freq: float, sampling_rate: float, amplitude: float = 1.0,
+                  phase: float = 0.0, block: int = 1024) -> np.ndarray:
+    # amplitude * sin(2π f n / fs + phase) from a rotating phasor instead of per-sample sin:
+    # within a block z[k] = z0 * w^k (w = exp(j 2π f / fs)) by cumulative product, and
+    # each block starts from an anchor advanced by w^block, renormalized to unit magnitude
+    if num_samples <= 0:
+        return np.zeros(0)
+    omega = 2.0 * np.pi * freq / sampling_rate
+    num_blocks = -(-num_samples // block)
+    inner = np.ones(block, dtype=complex)
+    inner[1:] = np.cumprod(np.full(block - 1, np.exp(1j * omega)))
+    anchors = np.ones(num_blocks, dtype=complex)
+    anchors[1:] = np.cumprod(np.full(num_blocks - 1, np.exp(1j * omega * block)))
+    anchors *= np.exp(1j * phase) / np.abs(anchors)
+    return amplitude * np.imag(np.outer(anchors, inner).ravel()[:num_samples])
+
+
+NORMALIZE_MODES = ("peak", "rms", "zscore")
+
+
+def normalize_signal(x: np.ndarray, mode: str = "peak") -> np.ndarray:
+    # peak: max |x| -> 1; rms: RMS -> 1; zscore: zero mean, unit standard deviation.
+    # An all-zero (or, for zscore, constant) signal has nothing to scale and is only shifted
+    x = np.asarray(x, dtype=float)
+    if mode == "zscore":
+        x = x - np.mean(x)
+        scale = np.std(x)
+    elif mode == "rms":
+        scale = np.sqrt(np.mean(x ** 2))
+    elif mode == "peak":
+        scale = np.max(np.abs(x)) if len(x) else 0.0
+    else:
+        raise ValueError(f"Unknown normalization {mode!r}, expected one of {', '.join(NORMALIZE_MODES)}")
+    return x / scale if scale > 0 else x.copy()
+
+
+@dataclass
+class Oscillator:
+    """
+    Sample-by-sample sine source using a complex-rotation recurrence.
+    
+    Each next() multiplies a unit phasor by exp(j 2π freq / sampling_rate)
+    instead of evaluating sin, and rescales it to unit magnitude every
+    renormalize_every samples so rounding errors cannot make it drift.
+    Sample n equals amplitude * sin(2π freq n / sampling_rate + phase).
+    Meant for streaming, one sample at a time; whole arrays are faster
+    with carrier_signal or rotation_sine.
+    """
+    freq: float
+    amplitude: float
+    sampling_rate: float
+    phase: float = 0.0
+    renormalize_every: int = 1024
+    
+    def __post_init__(self):
+        if self.sampling_rate <= 0:
+            raise ValueError(f"Sampling rate must be positive, got {self.sampling_rate}")
+        if self.renormalize_every < 1:
+            raise ValueError(f"Renormalization interval must be positive, got {self.renormalize_every}")
+        self._rotation = complex(np.exp(2j * np.pi * self.freq / self.sampling_rate))
+        self._phasor = complex(np.exp(1j * self.phase))
+        self._count = 0
+    
+    def next(self) -> float:
+        """Current sample; advances the oscillator by one sample period."""
+        value = self.amplitude * self._phasor.imag
+        self._phasor *= self._rotation
+        self._count += 1
+        if self._count % self.renormalize_every == 0:
+            self._phasor /= abs(self._phasor)
+        return value
+    
+    def generate(self, num_samples: int) -> np.ndarray:
+        """The next num_samples samples as an array."""
+        return np.array([self.next() for _ in range(num_samples)])
+
+
+AM_VARIANTS = ("full-carrier", "suppressed-carrier", "reduced-carrier")
+
+
+def am_carrier_level(variant: str, pilot_level: float = 0.1) -> float:
+    # Carrier term of the AM envelope relative to full carrier: 1 for DSB-LC,
+    # 0 for DSB-SC, pilot_level for a reduced (pilot) carrier
+    if variant == "full-carrier":
+        return 1.0
+    if variant == "suppressed-carrier":
+        return 0.0
+    if variant == "reduced-carrier":
+        if not 0.0 < pilot_level < 1.0:
+            raise ValueError(f"Pilot level must be in (0, 1), got {pilot_level}")
+        return float(pilot_level)
+    raise ValueError(f"Unknown AM variant {variant!r}, expected one of {', '.join(AM_VARIANTS)}")
+
 
+def am_modulate(m: np.ndarray, t: np.ndarray, carrier_freq: float, carrier_amplitude: float = 1.0, am_index: float = 0.5,
+                carrier_level: float = 1.0) -> np.ndarray:
+    # s_AM(t) = Ac * (c + ka*m(t)) * sin(2π f_c t), c = 1 for full carrier (see am_carrier_level)
+    return carrier_amplitude * (carrier_level + am_index * m) * np.sin(2.0 * np.pi * carrier_freq * t)
 
-def fm_modulate(m: np.ndarray, t: np.ndarray, carrier_freq: float, carrier_amplitude: float = 1.0, fm_deviation_hz: float = 5_000.0, sampling_rate: float | None = None) -> np.ndarray:
-    # s_FM(t) = Ac * sin(2π f_c t + 2π*Δf * ∫ m(τ) dτ)
+
+def am_modulate_clamped(m: np.ndarray, t: np.ndarray, carrier_freq: float, carrier_amplitude: float = 1.0, am_index: float = 0.5) -> Tuple[np.ndarray, int]:
+    # Like am_modulate, but an overmodulated envelope 1 + ka*m(t) < 0 is clipped to zero
+    # instead of reversing the carrier phase. Returns (signal, number of clamped samples).
+    envelope = 1.0 + am_index * np.asarray(m, dtype=float)
+    clamped = int(np.count_nonzero(envelope < 0.0))
+    return carrier_amplitude * np.maximum(envelope, 0.0) * np.sin(2.0 * np.pi * carrier_freq * t), clamped
+
+
+def pm_modulate(m: np.ndarray, t: np.ndarray, carrier_freq: float, carrier_amplitude: float = 1.0, pm_index: float = 1.0) -> np.ndarray:
+    # s_PM(t) = Ac * sin(2π f_c t + kp*m(t))
+    return carrier_amplitude * np.sin(2.0 * np.pi * carrier_freq * t + pm_index * m)
+
+
+def dsbsc_modulate(m: np.ndarray, t: np.ndarray, carrier_freq: float, carrier_amplitude: float = 1.0) -> np.ndarray:
+    # s_DSB-SC(t) = Ac * m(t) * cos(2π f_c t), no carrier term
+    return carrier_amplitude * m * np.cos(2.0 * np.pi * carrier_freq * t)
+
+
+def pre_emphasis(m: np.ndarray, sampling_rate: float, tau: float = 75e-6) -> np.ndarray:
+    # H(s) = 1 + s*tau (backward difference): boosts message content above 1/(2π tau)
+    dt = 1.0 / float(sampling_rate)
+    emphasized = np.array(m, dtype=float)
+    emphasized[1:] += (tau / dt) * np.diff(m)
+    return emphasized
+
+
+def fsk_modulate(bits: np.ndarray, sampling_rate: float, bit_rate: float, freq_space: float,
+                 freq_mark: float, carrier_amplitude: float = 1.0) -> np.ndarray:
+    # Continuous-phase binary FSK: bit 0 -> freq_space, bit 1 -> freq_mark
+    samples_per_bit = int(np.round(sampling_rate / bit_rate))
+    if samples_per_bit < 2:
+        raise ValueError("Sampling rate must give at least two samples per bit")
+    bits = np.asarray(bits, dtype=int)
+    inst_freq = np.repeat(np.where(bits > 0, freq_mark, freq_space), samples_per_bit).astype(float)
+    phase = 2.0 * np.pi * np.cumsum(inst_freq) / sampling_rate
+    return carrier_amplitude * np.sin(phase)
+
+
+QAM_ORDERS = (4, 16)
+
+
+def _gray_to_binary(value: int) -> int:
+    mask = value >> 1
+    while mask:
+        value ^= mask
+        mask >>= 1
+    return value
+
+
+def qam_constellation(order: int) -> np.ndarray:
+    """
+    Gray-coded square QAM constellation with unit average symbol energy.
+
+    Entry s is the point for symbol value s: its high log2(L) bits select
+    the I level and its low bits the Q level (L = sqrt(order)), each Gray
+    coded so neighbouring levels differ in one bit.
+    """
+    if order not in QAM_ORDERS:
+        raise ValueError(f"Unsupported QAM order {order}; expected one of {QAM_ORDERS}")
+    levels = int(np.sqrt(order))
+    bits_per_axis = levels.bit_length() - 1
+    points = np.zeros(order, dtype=complex)
+    for s in range(order):
+        i_level = 2 * _gray_to_binary(s >> bits_per_axis) - (levels - 1)
+        q_level = 2 * _gray_to_binary(s & (levels - 1)) - (levels - 1)
+        points[s] = complex(i_level, q_level)
+    return points / np.sqrt(2.0 * (order - 1) / 3.0)
+
+
+def qam_map_bits(bits: np.ndarray, order: int) -> np.ndarray:
+    # Groups of log2(order) bits, MSB first, -> constellation points
+    constellation = qam_constellation(order)
+    k = order.bit_length() - 1
+    bits = np.asarray(bits, dtype=int)
+    if len(bits) % k:
+        raise ValueError(f"Bit count {len(bits)} is not a multiple of {k} bits per symbol")
+    values = bits.reshape(-1, k) @ (1 << np.arange(k - 1, -1, -1))
+    return constellation[values]
+
+
+def qam_demap_symbols(symbols: np.ndarray, order: int) -> np.ndarray:
+    # Nearest constellation point -> log2(order) bits, MSB first
+    constellation = qam_constellation(order)
+    k = order.bit_length() - 1
+    values = np.argmin(np.abs(np.asarray(symbols)[:, None] - constellation[None, :]), axis=1)
+    return ((values[:, None] >> np.arange(k - 1, -1, -1)) & 1).reshape(-1)
+
+
+def qam_modulate(symbols: np.ndarray, sampling_rate: float, symbol_rate: float, carrier_freq: float,
+                 carrier_amplitude: float = 1.0) -> np.ndarray:
+    # s(t) = Ac * (I(t) cos(2π f_c t) - Q(t) sin(2π f_c t)) with rectangular symbol pulses
+    samples_per_symbol = int(np.round(sampling_rate / symbol_rate))
+    if samples_per_symbol < 2:
+        raise ValueError("Sampling rate must give at least two samples per symbol")
+    baseband = np.repeat(np.asarray(symbols, dtype=complex), samples_per_symbol)
+    t = np.arange(len(baseband)) / sampling_rate
+    return carrier_amplitude * (baseband.real * np.cos(2.0 * np.pi * carrier_freq * t)
+                                - baseband.imag * np.sin(2.0 * np.pi * carrier_freq * t))
+
+
+INTEGRATION_METHODS = ("rectangular", "trapezoidal", "simpson")
+
+
+def integrate_message(m: np.ndarray, dt: float, method: str = "rectangular") -> np.ndarray:
+    # Running integral ∫ m(τ) dτ sampled at each t[n]
+    if method == "rectangular":
+        return np.cumsum(m) * dt
+    if method == "trapezoidal" or (method == "simpson" and len(m) < 3):
+        integral = np.zeros(len(m))
+        integral[1:] = np.cumsum(0.5 * (m[1:] + m[:-1])) * dt
+        return integral
+    if method == "simpson":
+        # Composite Simpson at even samples; each odd sample adds one interval
+        # of the parabola through its neighbours (the last one looking back),
+        # so the error stays O(dt^4) instead of trapezoidal's O(dt^2)
+        m = np.asarray(m, dtype=float)
+        n = len(m)
+        integral = np.zeros(n)
+        integral[2::2] = np.cumsum(m[0:n - 2:2] + 4.0 * m[1:n - 1:2] + m[2::2]) * dt / 3.0
+        odd = np.arange(1, n - 1, 2)
+        integral[odd] = integral[odd - 1] + (5.0 * m[odd - 1] + 8.0 * m[odd] - m[odd + 1]) * dt / 12.0
+        if n % 2 == 0:
+            integral[-1] = integral[-2] + (-m[-3] + 8.0 * m[-2] + 5.0 * m[-1]) * dt / 12.0
+        return integral
+    raise ValueError(f"Unknown integration method: {method}")
+
+
+def fm_sensitivity(fm_deviation_hz: float) -> float:
+    """
+    Frequency sensitivity kf = 2π * fm_deviation_hz, in rad/s per unit of m(t).
+
+    fm_modulate advances the phase by kf * ∫ m(τ) dτ, so the instantaneous
+    frequency offset is kf * m(t) rad/s. The FM discriminators divide their
+    recovered offset in rad/s by this same kf, which makes them its exact
+    inverse.
+    """
+    return 2.0 * np.pi * fm_deviation_hz
+
+
+def fm_modulate(m: np.ndarray, t: np.ndarray, carrier_freq: float, carrier_amplitude: float = 1.0, fm_deviation_hz: float = 5_000.0, sampling_rate: float | None = None, integration: str = "rectangular") -> np.ndarray:
+    # s_FM(t) = Ac * sin(2π f_c t + kf * ∫ m(τ) dτ), kf = fm_sensitivity(Δf)
     if sampling_rate is None:
         # Derive from time vector assuming uniform spacing
         if len(t) < 2:
@@ -33,6 +347,114 @@ def fm_modulate(m: np.ndarray, t: np.ndarray, carrier_freq: float, carrier_ampli
         dt = float(np.mean(np.diff(t)))
     else:
         dt = 1.0 / float(sampling_rate)
-    integral_m = np.cumsum(m) * dt
-    phase = 2.0 * np.pi * carrier_freq * t + 2.0 * np.pi * fm_deviation_hz * integral_m
+    integral_m = integrate_message(m, dt, integration)
+    phase = 2.0 * np.pi * carrier_freq * t + fm_sensitivity(fm_deviation_hz) * integral_m
     return carrier_amplitude * np.sin(phase)
+
+
+# ----------------------- Recorded message sources -----------------------
+
+def _check_duration(num_samples: int, sampling_rate: float, expected_duration: float | None, filename: str) -> None:
+    if expected_duration is None:
+        return
+    expected = int(np.round(sampling_rate * expected_duration))
+    if num_samples != expected:
+        raise ValueError(
+            f"{filename}: expected {expected} samples for {expected_duration} s at {sampling_rate} Hz, got {num_samples}"
+        )
+
+
+def save_signals_csv(t: np.ndarray, signals: Dict[str, np.ndarray], filename: str) -> None:
+    """
+    Write several signals on one time base as a wide CSV.
+
+    The first column is Time, followed by one column per entry of signals
+    in insertion order, headed by its name.
+
+    Raises:
+        ValueError: If there are no signals or one does not match len(t)
+    """
+    if not signals:
+        raise ValueError("No signals to save")
+    columns = [np.asarray(t, dtype=float)]
+    for name, values in signals.items():
+        values = np.asarray(values, dtype=float)
+        if values.shape != columns[0].shape:
+            raise ValueError(f"Signal {name!r} has {values.size} samples, time base has {columns[0].size}")
+        columns.append(values)
+    with open(filename, 'w', newline='') as f:
+        writer = csv.writer(f)
+        writer.writerow(['Time'] + list(signals))
+        writer.writerows(np.column_stack(columns).tolist())
+
+
+def load_signal_csv(filename: str, expected_duration: float | None = None) -> Tuple[np.ndarray, np.ndarray, float]:
+    """
+    Load a message signal from a Time,Amplitude CSV file.
+
+    A non-numeric first row is treated as a header. The sampling rate is
+    detected from the (uniform) spacing of the Time column.
+
+    Returns:
+        (t, m, sampling_rate)
+    """
+    times = []
+    values = []
+    with open(filename, newline='') as f:
+        for line_no, row in enumerate(csv.reader(f), start=1):
+            if not row or all(cell.strip() == "" for cell in row):
+                continue
+            if len(row) < 2:
+                raise ValueError(f"{filename}:{line_no}: expected 2 columns (time, amplitude), got {len(row)}")
+            try:
+                time_value, amplitude = float(row[0]), float(row[1])
+            except ValueError:
+                if line_no == 1:
+                    continue
+                raise ValueError(f"{filename}:{line_no}: malformed row {row!r}")
+            times.append(time_value)
+            values.append(amplitude)
+
+    if len(times) < 2:
+        raise ValueError(f"{filename}: need at least two samples")
+    t = np.asarray(times, dtype=float)
+    m = np.asarray(values, dtype=float)
+    steps = np.diff(t)
+    dt = float(np.mean(steps))
+    if dt <= 0 or np.max(np.abs(steps - dt)) > 1e-6 * dt + 1e-12:
+        raise ValueError(f"{filename}: time column must be uniformly increasing")
+    sampling_rate = 1.0 / dt
+    _check_duration(len(m), sampling_rate, expected_duration, filename)
+    return t, m, sampling_rate
+
+
+def load_signal_wav(filename: str, expected_duration: float | None = None) -> Tuple[np.ndarray, np.ndarray, float]:
+    """
+    Load a PCM WAV file as a message signal scaled to [-1, 1].
+
+    Multi-channel audio is mixed down to mono.
+
+    Returns:
+        (t, m, sampling_rate)
+    """
+    with wave.open(filename, 'rb') as wav:
+        sampling_rate = float(wav.getframerate())
+        channels = wav.getnchannels()
+        width = wav.getsampwidth()
+        frames = wav.readframes(wav.getnframes())
+
+    if width == 1:
+        data = (np.frombuffer(frames, dtype=np.uint8).astype(float) - 128.0) / 128.0
+    elif width == 2:
+        data = np.frombuffer(frames, dtype='<i2').astype(float) / 32768.0
+    elif width == 4:
+        data = np.frombuffer(frames, dtype='<i4').astype(float) / 2147483648.0
+    else:
+        raise ValueError(f"{filename}: unsupported sample width {width} bytes")
+
+    m = data.reshape(-1, channels).mean(axis=1)
+    if len(m) == 0:
+        raise ValueError(f"{filename}: no audio frames")
+    _check_duration(len(m), sampling_rate, expected_duration, filename)
+    t = np.arange(len(m), dtype=float) / sampling_rate
+    return t, m, sampling_rate
@@ -2,9 +2,13 @@
 
 import unittest
 import sys
+import os
+import json
+import tempfile
 from unittest.mock import patch
 
-from config import SimulationParams, validate_params, choose_params, summarize_params
+from config import SimulationParams, validate_params, choose_params, summarize_params, check_params
+from config import parse_snr_range, load_params_json, save_params_json
 
 
 class TestConfigFunctions(unittest.TestCase):
@@ -60,10 +64,15 @@ class TestConfigFunctions(unittest.TestCase):
         validated = validate_params(invalid_params)
         self.assertGreater(validated.sampling_rate, 0)  # Should be corrected to default
         
-        # Test with AM index > 1
-        invalid_params = SimulationParams(am_index=1.5)
+        # AM index > 1 is overmodulation, not an error
+        overmodulated = SimulationParams(am_index=1.5)
+        validated = validate_params(overmodulated)
+        self.assertEqual(validated.am_index, 1.5)  # Should be kept
+        
+        # Test with negative AM index
+        invalid_params = SimulationParams(am_index=-0.5)
         validated = validate_params(invalid_params)
-        self.assertLessEqual(validated.am_index, 1.0)  # Should be clamped
+        self.assertEqual(validated.am_index, 0.5)  # Should be corrected to default
         
         # Test with negative trials
         invalid_params = SimulationParams(trials=-10)
@@ -82,6 +91,19 @@ class TestConfigFunctions(unittest.TestCase):
         validated = validate_params(invalid_params)
         self.assertLess(validated.message_freq, validated.sampling_rate / 2.0)
     
+    def test_check_params_raises(self):
+        """Test that strict checking reports bad values instead of repairing them."""
+        with self.assertRaises(ValueError) as ctx:
+            check_params(SimulationParams(sampling_rate=-1000.0))
+        self.assertIn("sampling_rate", str(ctx.exception))
+        
+        with self.assertRaises(ValueError) as ctx:
+            check_params(SimulationParams(sampling_rate=10000.0, carrier_freq=6000.0))
+        self.assertIn("Nyquist", str(ctx.exception))
+        
+        # Defaults pass untouched
+        check_params(SimulationParams())
+    
     def test_summarize_params(self):
         """Test parameter summary generation."""
         summary = summarize_params(self.default_params)
@@ -93,6 +115,7 @@ class TestConfigFunctions(unittest.TestCase):
         self.assertIn('fc:', summary)
         # String matches current summary format
         self.assertIn('AM index ka:', summary)
+        self.assertIn('AM variant: full-carrier', summary)
         self.assertIn('FM deviation:', summary)
         self.assertIn('SNR range (dB):', summary)
         self.assertIn('trials:', summary)
@@ -119,13 +142,71 @@ class TestConfigFunctions(unittest.TestCase):
             self.assertEqual(params.snr_min, 0.0)
             self.assertEqual(params.snr_max, 30.0)
     
+    def test_parse_snr_range(self):
+        """Test comma-separated SNR parsing, including empty and malformed input."""
+        self.assertEqual(parse_snr_range("0,5,10"), [0.0, 5.0, 10.0])
+        self.assertEqual(parse_snr_range(" 12.5, -3 ,12.5"), [-3.0, 12.5])
+        self.assertEqual(parse_snr_range("7"), [7.0])
+        
+        for text in ("", " ", ",", " , "):
+            with self.assertRaises(ValueError):
+                parse_snr_range(text)
+        for text in ("0,abc,10", "0,,10", "5;10", "nan", "inf"):
+            with self.assertRaises(ValueError):
+                parse_snr_range(text)
+    
+    def test_choose_params_snr_range(self):
+        """Test that --snr-range overrides the min/max/step sweep."""
+        with patch.object(sys, 'argv', ['main.py', '--snr-range', '20,0,10']):
+            params = choose_params()
+        self.assertEqual(params.snr_values, [0.0, 10.0, 20.0])
+        self.assertIn('SNR range (dB): 0.0, 10.0, 20.0', summarize_params(params))
+        
+        with patch.object(sys, 'argv', ['main.py', '--snr-range', '0,x']):
+            with self.assertRaises(SystemExit):
+                choose_params()
+    
+    def test_params_json_round_trip(self):
+        """Test that saved parameters reload unchanged."""
+        params = SimulationParams(sampling_rate=48000.0, carrier_freq=12000.0, trials=25,
+                                  am_demodulator="coherent", include_pm=True,
+                                  snr_values=[0.0, 7.5, 15.0], seed=3, workers=2)
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "params.json")
+            save_params_json(params, path)
+            self.assertEqual(load_params_json(path), params)
+            
+            # CLI flags override values from the file
+            with patch.object(sys, 'argv', ['main.py', '--config', path, '--trials', '5']):
+                chosen = choose_params()
+            self.assertEqual(chosen.trials, 5)
+            self.assertEqual(chosen.sampling_rate, 48000.0)
+    
+    def test_params_json_errors(self):
+        """Test missing files, unknown fields and invalid values."""
+        with tempfile.TemporaryDirectory() as tmp:
+            with self.assertRaises(FileNotFoundError):
+                load_params_json(os.path.join(tmp, "missing.json"))
+            
+            path = os.path.join(tmp, "params.json")
+            for payload in ({"sampling_rte": 1000.0}, {"sampling_rate": -1000.0}, [1, 2]):
+                with open(path, "w") as f:
+                    json.dump(payload, f)
+                with self.assertRaises(ValueError):
+                    load_params_json(path)
+            
+            with open(path, "w") as f:
+                f.write("{not json")
+            with self.assertRaises(ValueError):
+                load_params_json(path)
+    
     def test_choose_params_validation(self):
         """Test that choose_params validates parameters."""
         # Test with invalid arguments that should be corrected
         test_args = [
             'main.py',
             '--fs', '-1000',  # Invalid negative sampling rate
-            '--am-index', '2.0',  # Invalid AM index > 1
+            '--am-index', '-2.0',  # Invalid negative AM index
             '--trials', '-10'  # Invalid negative trials
         ]
         
@@ -134,7 +215,7 @@ class TestConfigFunctions(unittest.TestCase):
             
             # Check that invalid parameters were corrected
             self.assertGreater(params.sampling_rate, 0)
-            self.assertLessEqual(params.am_index, 1.0)
+            self.assertEqual(params.am_index, 0.5)
             self.assertGreater(params.trials, 0)
     
     def test_edge_cases(self):
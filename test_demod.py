@@ -4,7 +4,12 @@ import unittest
 import numpy as np
 
 from signals import generate_time_vector, message_signal, am_modulate, fm_modulate
-from demod import am_demodulate_envelope, fm_demodulate_instantaneous_frequency, fm_demodulate_quadrature
+from demod import am_demodulate_envelope, am_demodulate_hilbert, fm_demodulate_instantaneous_frequency, fm_demodulate_quadrature
+from demod import fm_demodulate_pll, dsbsc_demodulate_costas, de_emphasis, fsk_demodulate, pm_demodulate
+from demod import unwrap_phase, instantaneous_phase, instantaneous_frequency, am_demodulate_coherent
+from demod import qam_demodulate, recover_symbol_timing, hard_limiter, matched_filter
+from demod import IQImbalance, correct_iq_imbalance, qam_soft_symbols, estimate_frequency_offset
+from demod import DIODE_MODELS, diode_rectify
 
 
 class TestDemodulation(unittest.TestCase):
@@ -52,6 +57,81 @@ class TestDemodulation(unittest.TestCase):
         correlation = np.corrcoef(self.message, demodulated)[0, 1]
         self.assertGreaterEqual(correlation, 0.38)
     
+    def test_am_envelope_diode_models(self):
+        """Test every diode model recovers the envelope of a clean, well-sampled AM signal."""
+        x = np.array([-1.0, -0.2, 0.0, 0.5])
+        self.assertTrue(np.array_equal(diode_rectify(x, "full-wave"), [1.0, 0.2, 0.0, 0.5]))
+        self.assertTrue(np.array_equal(diode_rectify(x, "half-wave"), [0.0, 0.0, 0.0, 0.5]))
+        soft = diode_rectify(x, "exponential", knee_voltage=0.01)
+        self.assertTrue(np.allclose(soft, diode_rectify(x, "half-wave"), atol=0.01))
+        with self.assertRaises(ValueError):
+            diode_rectify(x, "bridge")
+        
+        fs, fc = 100000.0, 10000.0
+        t = generate_time_vector(fs, self.duration)
+        message = message_signal(t, self.message_freq, self.amplitude)
+        am_signal = am_modulate(message, t, fc, self.amplitude, self.am_index)
+        outputs = {}
+        for model in DIODE_MODELS:
+            with self.subTest(diode=model):
+                outputs[model] = am_demodulate_envelope(am_signal, t, fc, self.amplitude,
+                                                        cutoff_hz=2 * self.message_freq, diode=model)
+                self.assertGreater(np.corrcoef(message[500:-500], outputs[model][500:-500])[0, 1], 0.8)
+        # Scaled by 2, the half-wave output has the full-wave message gain
+        self.assertAlmostEqual(np.std(outputs["half-wave"]) / np.std(outputs["full-wave"]), 1.0, delta=0.05)
+    
+    def test_am_demodulation_hilbert_clean_signal(self):
+        """Test Hilbert envelope AM demodulation with a clean, well-sampled signal."""
+        t = generate_time_vector(100000.0, 0.01)
+        message = message_signal(t, 1000.0, 1.0)
+        am_signal = am_modulate(message, t, 10000.0, 1.0, 0.5)
+
+        demodulated = am_demodulate_hilbert(am_signal, t, 10000.0, 1.0)
+
+        self.assertEqual(len(demodulated), len(message))
+        correlation = np.corrcoef(message, demodulated)[0, 1]
+        self.assertGreater(correlation, 0.95)
+
+    def test_am_demodulation_coherent_overmodulated(self):
+        """Test that coherent detection recovers an over-modulated message the envelope folds."""
+        t = generate_time_vector(100000.0, 0.01)
+        message = message_signal(t, 1000.0, 1.0)
+        am_signal = am_modulate(message, t, 10000.0, 1.0, 1.5)
+        
+        coherent = am_demodulate_coherent(am_signal, t, 10000.0, 1.0, cutoff_hz=2000.0)
+        envelope = am_demodulate_hilbert(am_signal, t, 10000.0, 1.0, cutoff_hz=2000.0)
+        
+        trim = len(t) // 10
+        coherent_corr = np.corrcoef(message[trim:-trim], coherent[trim:-trim])[0, 1]
+        envelope_corr = np.corrcoef(message[trim:-trim], envelope[trim:-trim])[0, 1]
+        self.assertGreater(coherent_corr, 0.95)
+        self.assertGreater(coherent_corr, envelope_corr)
+        
+        # A carrier phase offset is tracked out and the output keeps its sign
+        shifted = (1.0 + 0.5 * message) * np.sin(2 * np.pi * 10000.0 * t + 2.0)
+        recovered = am_demodulate_coherent(shifted, t, 10000.0, 1.0, cutoff_hz=2000.0)
+        self.assertGreater(np.corrcoef(message[trim:-trim], recovered[trim:-trim])[0, 1], 0.95)
+    
+    def test_am_coherent_beats_envelope_in_noise(self):
+        """Test that coherent detection gives a higher output SNR than envelope detection at 10 dB."""
+        from noise import add_gaussian_noise
+        from utils import calculate_output_snr_aligned
+        
+        fs = 100000.0
+        t = generate_time_vector(fs, 0.02)
+        message = message_signal(t, 1000.0, 1.0)
+        am_signal = am_modulate(message, t, 10000.0, 1.0, 0.5)
+        
+        coherent_snrs, envelope_snrs = [], []
+        for seed in range(5):
+            noisy = add_gaussian_noise(am_signal, 10.0, seed=seed)
+            coherent = am_demodulate_coherent(noisy, t, 10000.0, 1.0, cutoff_hz=2000.0)
+            envelope = am_demodulate_envelope(noisy, t, 10000.0, 1.0, cutoff_hz=2000.0)
+            coherent_snrs.append(calculate_output_snr_aligned(message, coherent, fs, 1000.0))
+            envelope_snrs.append(calculate_output_snr_aligned(message, envelope, fs, 1000.0))
+        
+        self.assertGreater(np.mean(coherent_snrs), np.mean(envelope_snrs))
+    
     def test_fm_demodulation_instantaneous_frequency(self):
         """Test FM demodulation using instantaneous frequency method."""
         demodulated = fm_demodulate_instantaneous_frequency(self.fm_signal, self.t, 
@@ -76,6 +156,340 @@ class TestDemodulation(unittest.TestCase):
         correlation = np.corrcoef(self.message, demodulated)[0, 1]
         self.assertGreaterEqual(correlation, -0.2)
     
+    def test_fm_demodulation_pll(self):
+        """Test PLL FM demodulation tracks the message on a well-sampled signal."""
+        t = generate_time_vector(100000.0, 0.01)
+        message = message_signal(t, 1000.0, 1.0)
+        fm_signal = fm_modulate(message, t, 10000.0, 1.0, 5000.0, 100000.0)
+
+        demodulated = fm_demodulate_pll(fm_signal, t, 10000.0, 5000.0)
+
+        self.assertEqual(len(demodulated), len(message))
+        correlation = np.corrcoef(message, demodulated)[0, 1]
+        self.assertGreater(correlation, 0.9)
+        # Control voltage is scaled back to message units
+        self.assertAlmostEqual(np.std(demodulated[100:]), np.std(message[100:]), delta=0.2)
+
+    def test_fm_demodulation_recovers_amplitude(self):
+        """Test that every FM discriminator is the exact inverse of fm_modulate's scaling."""
+        t = generate_time_vector(100000.0, 0.02)
+        message = message_signal(t, 1000.0, 0.8)
+        fm_signal = fm_modulate(message, t, 10000.0, 1.0, 3000.0, 100000.0)
+        
+        demodulators = {
+            "instantaneous": fm_demodulate_instantaneous_frequency(fm_signal, t, 10000.0, 3000.0, cutoff_hz=2000.0),
+            "quadrature": fm_demodulate_quadrature(fm_signal, t, 10000.0, 3000.0),
+            "pll": fm_demodulate_pll(fm_signal, t, 10000.0, 3000.0),
+        }
+        # Away from the filter edges and the PLL acquisition transient
+        steady = slice(300, -300)
+        for name, demodulated in demodulators.items():
+            with self.subTest(demodulator=name):
+                peak = np.max(np.abs(demodulated[steady]))
+                self.assertAlmostEqual(peak, 0.8, delta=0.08)
+                self.assertGreater(np.corrcoef(message[steady], demodulated[steady])[0, 1], 0.95)
+
+    def test_fm_demodulation_time_aligned(self):
+        """Test that the FM output lines up with the message without an alignment search."""
+        from filters import design_fir_lowpass
+        
+        fs = 100000.0
+        t = generate_time_vector(fs, 0.02)
+        message = message_signal(t, 1000.0, 1.0)
+        fm_signal = fm_modulate(message, t, 10000.0, 1.0, 3000.0, fs)
+        
+        def delay_samples(y):
+            # Phase of the 1 kHz tone from a least-squares sine/cosine fit
+            steady = slice(300, -300)
+            basis = np.vstack([np.sin(2 * np.pi * 1000.0 * t), np.cos(2 * np.pi * 1000.0 * t)]).T[steady]
+            a, b = np.linalg.lstsq(basis, y[steady], rcond=None)[0]
+            return -np.arctan2(b, a) / (2 * np.pi * 1000.0) * fs
+        
+        self.assertAlmostEqual(delay_samples(message), 0.0, places=6)
+        zero_phase = fm_demodulate_instantaneous_frequency(fm_signal, t, 10000.0, 3000.0, cutoff_hz=2000.0)
+        fir = fm_demodulate_instantaneous_frequency(fm_signal, t, 10000.0, 3000.0, cutoff_hz=2000.0, num_taps=61)
+        self.assertLess(abs(delay_samples(zero_phase)), 1.0)
+        self.assertLess(abs(delay_samples(fir)), 1.0)
+        
+        # Without compensation the same FIR lags by its group delay
+        raw = fm_demodulate_instantaneous_frequency(fm_signal, t, 10000.0, 3000.0)
+        lowpass = design_fir_lowpass(2000.0, fs, 61)
+        self.assertEqual(lowpass.group_delay, 30)
+        self.assertAlmostEqual(delay_samples(lowpass.apply(raw, compensate=False)), 30.0, delta=1.0)
+
+    def test_hard_limiter(self):
+        """Test clipping to the threshold and the threshold guard."""
+        limited = hard_limiter(self.fm_signal, 0.5)
+        self.assertEqual(len(limited), len(self.fm_signal))
+        self.assertAlmostEqual(np.max(np.abs(limited)), 0.5)
+        small = np.abs(self.fm_signal) < 0.5
+        self.assertTrue(np.array_equal(limited[small], self.fm_signal[small]))
+        with self.assertRaises(ValueError):
+            hard_limiter(self.fm_signal, 0.0)
+
+    def test_dsbsc_demodulation_costas(self):
+        """Test Costas loop DSB-SC demodulation, including an unknown carrier phase."""
+        from signals import dsbsc_modulate
+        
+        t = generate_time_vector(100000.0, 0.05)
+        message = message_signal(t, 1000.0, 1.0)
+        dsb_signal = dsbsc_modulate(message, t, 10000.0, 1.0)
+        
+        demodulated = dsbsc_demodulate_costas(dsb_signal, t, 10000.0, 1.0, message_freq=1000.0)
+        self.assertEqual(len(demodulated), len(message))
+        self.assertGreater(np.corrcoef(message, demodulated)[0, 1], 0.9)
+        
+        # Carrier phase offset must be recovered by the loop
+        offset_signal = message * np.cos(2 * np.pi * 10000.0 * t + 0.6)
+        demodulated = dsbsc_demodulate_costas(offset_signal, t, 10000.0, 1.0, message_freq=1000.0)
+        half = len(t) // 2
+        self.assertGreater(np.corrcoef(message[half:], demodulated[half:])[0, 1], 0.9)
+
+        # An explicit cutoff_hz takes precedence over the 2.5*fm default
+        noisy = dsb_signal + 0.3 * np.random.default_rng(0).standard_normal(len(t))
+        freqs = np.fft.rfftfreq(len(t), 1.0 / 100000.0)
+        band = (freqs > 1500.0) & (freqs < 2500.0)
+        narrow = dsbsc_demodulate_costas(noisy, t, 10000.0, 1.0, message_freq=1000.0, cutoff_hz=1200.0)
+        wide = dsbsc_demodulate_costas(noisy, t, 10000.0, 1.0, message_freq=1000.0)
+        self.assertLess(np.sum(np.abs(np.fft.rfft(narrow)[band]) ** 2),
+                        0.5 * np.sum(np.abs(np.fft.rfft(wide)[band]) ** 2))
+    
+    def test_de_emphasis_inverts_pre_emphasis(self):
+        """Test that de-emphasis exactly undoes pre-emphasis."""
+        from signals import pre_emphasis
+        
+        rng = np.random.default_rng(3)
+        x = rng.standard_normal(500)
+        emphasized = pre_emphasis(x, self.sampling_rate, 75e-6)
+        
+        # Pre-emphasis boosts high-frequency content
+        self.assertGreater(np.std(np.diff(emphasized)), np.std(np.diff(x)))
+        self.assertTrue(np.allclose(de_emphasis(emphasized, self.sampling_rate, 75e-6), x))
+    
+    def test_fsk_demodulation_clean(self):
+        """Test that FSK correlators recover every bit of a clean signal."""
+        from signals import fsk_modulate
+        
+        bits = np.random.default_rng(7).integers(0, 2, 200)
+        fsk_signal = fsk_modulate(bits, 100000.0, 1000.0, 9000.0, 11000.0)
+        received = fsk_demodulate(fsk_signal, 100000.0, 1000.0, 9000.0, 11000.0)
+        
+        self.assertTrue(np.array_equal(received, bits))
+        self.assertEqual(len(fsk_demodulate(fsk_signal[:50], 100000.0, 1000.0, 9000.0, 11000.0)), 0)
+    
+    def test_fsk_matched_filter_lowers_ber(self):
+        """Test that coherent matched filtering beats the non-coherent correlator in noise."""
+        from signals import fsk_modulate
+        from noise import add_awgn_ebn0
+        
+        # A template matched to itself peaks at the last sample with the template energy
+        template = np.sin(2 * np.pi * np.arange(1, 101) / 10.0)
+        output = matched_filter(np.concatenate([template, np.zeros(50)]), template)
+        self.assertEqual(int(np.argmax(output)), 99)
+        self.assertAlmostEqual(output[99], np.sum(template ** 2))
+        
+        bits = np.random.default_rng(8).integers(0, 2, 4000)
+        fsk_signal = fsk_modulate(bits, 100000.0, 1000.0, 9000.0, 11000.0)
+        self.assertTrue(np.array_equal(fsk_demodulate(fsk_signal, 100000.0, 1000.0, 9000.0, 11000.0,
+                                                      detector="matched"), bits))
+        
+        # At 6 dB Eb/N0 theory gives ~2.3% (coherent) vs ~6.8% (non-coherent)
+        noisy = add_awgn_ebn0(fsk_signal, 6.0, 1000.0, 100000.0, seed=9)
+        correlator_ber = np.mean(fsk_demodulate(noisy, 100000.0, 1000.0, 9000.0, 11000.0) != bits)
+        matched_ber = np.mean(fsk_demodulate(noisy, 100000.0, 1000.0, 9000.0, 11000.0, detector="matched") != bits)
+        self.assertLess(matched_ber, correlator_ber)
+        
+        with self.assertRaises(ValueError):
+            fsk_demodulate(fsk_signal, 100000.0, 1000.0, 9000.0, 11500.0, detector="matched")
+    
+    def test_qam_demodulation(self):
+        """Test QAM symbol error rate at 20 dB and its growth with order at fixed SNR."""
+        from signals import qam_map_bits, qam_modulate, qam_demap_symbols
+        from noise import add_gaussian_noise
+        from utils import calculate_ber
+        
+        fs, symbol_rate, fc = 10000.0, 500.0, 2500.0
+        rng = np.random.default_rng(0)
+        
+        def symbol_error_rate(order, snr_db, num_symbols=1000):
+            k = order.bit_length() - 1
+            bits = rng.integers(0, 2, num_symbols * k)
+            symbols = qam_map_bits(bits, order)
+            received = add_gaussian_noise(qam_modulate(symbols, fs, symbol_rate, fc), snr_db, seed=order)
+            decided = qam_demodulate(received, fs, symbol_rate, fc, order)
+            ber = calculate_ber(bits, qam_demap_symbols(decided, order))
+            return np.mean(~np.isclose(decided, symbols)), ber
+        
+        # Clean channel decodes exactly
+        clean = qam_map_bits(rng.integers(0, 2, 64), 16)
+        self.assertTrue(np.allclose(qam_demodulate(qam_modulate(clean, fs, symbol_rate, fc), fs, symbol_rate, fc, 16), clean))
+        
+        ser_16, ber_16 = symbol_error_rate(16, 20.0)
+        self.assertLess(ser_16, 1e-2)
+        self.assertLess(ber_16, 1e-2)
+        
+        ser_4_low, _ = symbol_error_rate(4, 0.0)
+        ser_16_low, _ = symbol_error_rate(16, 0.0)
+        self.assertLess(ser_4_low, ser_16_low)
+        self.assertGreater(ser_16_low, 0.05)
+    
+    def test_frequency_offset_estimation(self):
+        """Test that a 50 Hz carrier offset is recovered from AM and FM signals."""
+        from noise import apply_frequency_offset, add_gaussian_noise
+        
+        # Well below Nyquist so the spectra are not folded
+        fs, fc = 100000.0, 10000.0
+        t = generate_time_vector(fs, self.duration)
+        message = message_signal(t, self.message_freq, self.amplitude)
+        signals = (("am", am_modulate(message, t, fc, self.amplitude, self.am_index)),
+                   ("fm", fm_modulate(message, t, fc, self.amplitude, self.fm_deviation, fs)))
+        for name, clean in signals:
+            with self.subTest(modulation=name):
+                self.assertAlmostEqual(estimate_frequency_offset(clean, fs, fc), 0.0, delta=3.0)
+                shifted = apply_frequency_offset(clean, 50.0, fs)
+                noisy = add_gaussian_noise(shifted, 20.0, seed=11)
+                self.assertAlmostEqual(estimate_frequency_offset(noisy, fs, fc), 50.0, delta=3.0)
+    
+    def test_iq_imbalance_correction(self):
+        """Test that 1 dB / 5 degree I/Q imbalance raises EVM and Gram-Schmidt correction lowers it."""
+        from signals import qam_map_bits, qam_modulate
+        from noise import add_gaussian_noise
+        from utils import calculate_evm
+        
+        fs, symbol_rate, fc = 10000.0, 500.0, 2500.0
+        symbols = qam_map_bits(np.random.default_rng(3).integers(0, 2, 4 * 2000), 16)
+        received = add_gaussian_noise(qam_modulate(symbols, fs, symbol_rate, fc), 30.0, seed=3)
+        soft = qam_soft_symbols(received, fs, symbol_rate, fc)
+        
+        def evm_percent(estimates):
+            return calculate_evm(estimates, symbols)
+        
+        imbalanced = IQImbalance(gain_db=1.0, phase_deg=5.0).apply(soft)
+        self.assertTrue(np.allclose(IQImbalance().apply(soft), soft))
+        self.assertGreater(evm_percent(imbalanced), 2.0 * evm_percent(soft))
+        self.assertLess(evm_percent(correct_iq_imbalance(imbalanced)), 0.5 * evm_percent(imbalanced))
+        
+        # The demodulator applies the same model and correction before deciding
+        decided = qam_demodulate(received, fs, symbol_rate, fc, 16,
+                                 iq_imbalance=IQImbalance(1.0, 5.0), correct_imbalance=True)
+        self.assertTrue(np.allclose(decided, symbols))
+    
+    def test_symbol_timing_recovery(self):
+        """Test Gardner timing recovery against a fractional-sample timing offset."""
+        sps, oversample, offset = 16, 10, 5.3
+        symbols = np.random.default_rng(3).choice([-1.0, 1.0], 300)
+        # Smooth bipolar pulses on a fine grid, delayed by a fractional number of coarse samples
+        fine = np.convolve(np.repeat(symbols, sps * oversample), np.hanning(sps * oversample), mode="same")
+        fine /= np.max(np.abs(fine))
+        delay = int(round(offset * oversample))
+        x = np.concatenate((np.zeros(delay), fine))[::oversample]
+        
+        centres = recover_symbol_timing(x, sps)
+        self.assertGreater(len(centres), 250)
+        # Symbol k is centred at sps*k + sps/2 + offset; ignore the acquisition phase
+        error = (centres[50:] - (0.5 * sps + offset) + 0.5 * sps) % sps - 0.5 * sps
+        self.assertLess(np.mean(np.abs(error)), 0.6)
+        self.assertLess(np.max(np.abs(error)), 1.5)
+        
+        with self.assertRaises(ValueError):
+            recover_symbol_timing(x, 1)
+    
+    def test_fsk_qam_with_timing_offset(self):
+        """Test that timing recovery realigns FSK and QAM symbols after a delay."""
+        from signals import fsk_modulate, qam_map_bits, qam_modulate
+        
+        rng = np.random.default_rng(11)
+        bits = rng.integers(0, 2, 200)
+        fsk_signal = np.concatenate((np.zeros(60), fsk_modulate(bits, 100000.0, 1000.0, 9000.0, 11000.0)))
+        self.assertFalse(np.array_equal(fsk_demodulate(fsk_signal, 100000.0, 1000.0, 9000.0, 11000.0), bits))
+        recovered = fsk_demodulate(fsk_signal, 100000.0, 1000.0, 9000.0, 11000.0, timing_recovery=True)
+        self.assertTrue(np.array_equal(recovered, bits))
+        
+        # Delay by whole carrier periods so only the symbol timing is disturbed
+        fs, symbol_rate, fc = 10000.0, 500.0, 2500.0
+        symbols = qam_map_bits(rng.integers(0, 2, 800), 16)
+        qam_signal = np.concatenate((np.zeros(8), qam_modulate(symbols, fs, symbol_rate, fc)))
+        misaligned = qam_demodulate(qam_signal, fs, symbol_rate, fc, 16)
+        self.assertFalse(np.allclose(misaligned, symbols))
+        decided = qam_demodulate(qam_signal, fs, symbol_rate, fc, 16, timing_recovery=True)
+        self.assertTrue(np.allclose(decided, symbols))
+    
+    def test_pm_demodulation_vs_fm(self):
+        """Test that PM demodulation recovers m(t) directly while FM needs integration."""
+        from signals import pm_modulate
+        
+        fs = 100000.0
+        t = generate_time_vector(fs, 0.01)
+        message = message_signal(t, 1000.0, 1.0)
+        pm_signal = pm_modulate(message, t, 10000.0, 1.0, 1.0)
+        
+        pm_demodulated = pm_demodulate(pm_signal, t, 10000.0, 1.0)
+        self.assertEqual(len(pm_demodulated), len(message))
+        self.assertGreater(np.corrcoef(message, pm_demodulated)[0, 1], 0.95)
+        self.assertAlmostEqual(np.std(pm_demodulated), np.std(message), delta=0.05)
+        
+        # An FM discriminator sees kp*dm/dt: uncorrelated with m until integrated
+        fm_demodulated = fm_demodulate_instantaneous_frequency(pm_signal, t, 10000.0, 1.0)
+        self.assertLess(abs(np.corrcoef(message, fm_demodulated)[0, 1]), 0.2)
+        integrated = np.cumsum(fm_demodulated) / fs * 2.0 * np.pi
+        integrated = integrated - np.mean(integrated)
+        self.assertGreater(np.corrcoef(message, integrated)[0, 1], 0.95)
+    
+    def test_unwrap_phase(self):
+        """Test that unwrapping removes 2π jumps from a wrapped phase ramp."""
+        true_phase = np.linspace(0, 40 * np.pi, 1000) + 0.3 * np.sin(np.linspace(0, 10, 1000))
+        wrapped = np.angle(np.exp(1j * true_phase))
+        
+        unwrapped = unwrap_phase(wrapped)
+        self.assertTrue(np.allclose(unwrapped, true_phase))
+        self.assertTrue(np.allclose(unwrapped, np.unwrap(wrapped)))
+        self.assertEqual(len(unwrap_phase(np.array([1.0]))), 1)
+    
+    def test_instantaneous_frequency_chirp(self):
+        """Test that instantaneous frequency tracks a linear chirp within 5%."""
+        fs = 100000.0
+        t = generate_time_vector(fs, 0.1)
+        f0, f1 = 1000.0, 5000.0
+        sweep_rate = (f1 - f0) / 0.1
+        chirp = np.cos(2 * np.pi * (f0 * t + 0.5 * sweep_rate * t ** 2))
+        
+        freq = instantaneous_frequency(chirp, fs)
+        expected = f0 + sweep_rate * t
+        
+        # Ignore Hilbert-transform edge effects
+        trim = len(t) // 20
+        relative_error = np.abs(freq[trim:-trim] - expected[trim:-trim]) / expected[trim:-trim]
+        self.assertLess(np.max(relative_error), 0.05)
+        
+        phase = instantaneous_phase(chirp)
+        self.assertTrue(np.all(np.diff(phase[trim:-trim]) > 0))
+    
+    def test_cutoff_independent_of_sampling_rate(self):
+        """Test that a fixed cutoff gives comparable demodulation at 8 kHz and 48 kHz."""
+        message_freq = 100.0
+        carrier_freq = 1500.0
+        correlations = {}
+        for fs in (8000.0, 48000.0):
+            t = generate_time_vector(fs, 0.2)
+            message = message_signal(t, message_freq, 1.0)
+            am = am_modulate(message, t, carrier_freq, 1.0, 0.5)
+            fm = fm_modulate(message, t, carrier_freq, 1.0, 300.0, fs)
+            
+            am_out = am_demodulate_envelope(am, t, carrier_freq, 1.0, cutoff_hz=2 * message_freq)
+            fm_out = fm_demodulate_instantaneous_frequency(fm, t, carrier_freq, 300.0,
+                                                          cutoff_hz=2 * message_freq)
+            trim = len(t) // 10
+            correlations[fs] = (
+                np.corrcoef(message[trim:-trim], am_out[trim:-trim])[0, 1],
+                np.corrcoef(message[trim:-trim], fm_out[trim:-trim])[0, 1],
+            )
+        
+        for am_corr, fm_corr in correlations.values():
+            self.assertGreater(am_corr, 0.95)
+            self.assertGreater(fm_corr, 0.95)
+        self.assertAlmostEqual(correlations[8000.0][0], correlations[48000.0][0], delta=0.05)
+        self.assertAlmostEqual(correlations[8000.0][1], correlations[48000.0][1], delta=0.05)
+    
     def test_demodulation_with_noise(self):
         """Test demodulation with noisy signals."""
         from noise import add_gaussian_noise
@@ -0,0 +1,159 @@
+"""Unit tests for the radix-2 FFT module."""
+
+import csv
+import os
+import tempfile
+import unittest
+import numpy as np
+
+from fft import fft, ifft, next_pow2, welch_psd, occupied_bandwidth, sideband_powers, save_spectrum_csv
+
+
+class TestFFT(unittest.TestCase):
+    """Test FFT functions."""
+
+    def test_next_pow2(self):
+        """Test power-of-two rounding."""
+        self.assertEqual(next_pow2(0), 1)
+        self.assertEqual(next_pow2(1), 1)
+        self.assertEqual(next_pow2(5), 8)
+        self.assertEqual(next_pow2(1024), 1024)
+        self.assertEqual(next_pow2(1025), 2048)
+
+    def test_sinusoid_single_peak(self):
+        """Test that a pure sinusoid produces a single peak at the right bin."""
+        n = 256
+        k = 10
+        x = np.sin(2 * np.pi * k * np.arange(n) / n)
+
+        magnitude = np.abs(fft(x))[:n // 2]
+
+        self.assertEqual(int(np.argmax(magnitude)), k)
+        others = np.delete(magnitude, k)
+        self.assertLess(np.max(others), 1e-9 * magnitude[k])
+
+    def test_matches_numpy(self):
+        """Test agreement with numpy's FFT."""
+        rng = np.random.default_rng(0)
+        x = rng.standard_normal(512)
+        self.assertTrue(np.allclose(fft(x), np.fft.fft(x), atol=1e-9))
+
+    def test_round_trip(self):
+        """Test that ifft(fft(x)) recovers x."""
+        rng = np.random.default_rng(1)
+        x = rng.standard_normal(1024)
+        self.assertLess(np.max(np.abs(ifft(fft(x)) - x)), 1e-9)
+
+    def test_zero_padding(self):
+        """Test that non-power-of-two inputs are zero-padded."""
+        x = np.ones(100)
+        spectrum = fft(x)
+        self.assertEqual(len(spectrum), 128)
+        recovered = ifft(spectrum)
+        self.assertTrue(np.allclose(recovered[:100], 1.0))
+        self.assertTrue(np.allclose(recovered[100:], 0.0))
+
+        with self.assertRaises(ValueError):
+            ifft(np.ones(100, dtype=complex))
+
+    def test_welch_two_tone_peaks(self):
+        """Test that a two-tone input gives two distinct PSD peaks at the tone frequencies."""
+        fs = 8192.0
+        t = np.arange(16384) / fs
+        rng = np.random.default_rng(2)
+        x = np.sin(2 * np.pi * 1024.0 * t) + 0.5 * np.sin(2 * np.pi * 2560.0 * t)
+        x += 0.01 * rng.standard_normal(len(t))
+
+        freqs, power_db = welch_psd(x, fs, segment_length=256, overlap=128)
+        self.assertEqual(len(freqs), 129)
+        resolution = freqs[1] - freqs[0]
+
+        first = int(np.argmax(power_db))
+        masked = power_db.copy()
+        masked[max(first - 5, 0):first + 6] = -np.inf
+        second = int(np.argmax(masked))
+
+        peaks = sorted([freqs[first], freqs[second]])
+        self.assertAlmostEqual(peaks[0], 1024.0, delta=resolution)
+        self.assertAlmostEqual(peaks[1], 2560.0, delta=resolution)
+
+        # Stronger tone is ~6 dB above the weaker one, both far above the floor between them
+        self.assertAlmostEqual(power_db[first] - power_db[second], 6.0, delta=1.5)
+        between = power_db[(freqs > 1500.0) & (freqs < 2000.0)]
+        self.assertGreater(power_db[second] - np.max(between), 30.0)
+
+    def test_welch_invalid_arguments(self):
+        """Test segment and overlap guards."""
+        x = np.ones(100)
+        with self.assertRaises(ValueError):
+            welch_psd(x, 1000.0, segment_length=256)
+        with self.assertRaises(ValueError):
+            welch_psd(x, 1000.0, segment_length=64, overlap=64)
+
+
+    def test_windowed_bandwidth_leakage(self):
+        """Test that windowing keeps off-bin tone leakage out of the 99% bandwidth."""
+        fs = 10000.0
+        t = np.arange(8192) / fs
+        # Both tones fall between bins of a 1024-point segment
+        x = np.sin(2 * np.pi * 1000.0 * t) + np.sin(2 * np.pi * 1305.0 * t)
+
+        rectangular = occupied_bandwidth(x, fs, window="rectangular")
+        hann = occupied_bandwidth(x, fs)
+        padded = occupied_bandwidth(x, fs, zero_pad=4)
+
+        self.assertLess(hann, 400.0)
+        self.assertGreater(rectangular, hann + 50.0)
+        self.assertLess(abs(padded - 305.0), 60.0)
+        with self.assertRaises(ValueError):
+            welch_psd(x, fs, zero_pad=0)
+
+    def test_sideband_powers(self):
+        """Test AM keeps a carrier with equal sidebands while SSB has a single sideband."""
+        from signals import am_modulate, dsbsc_modulate, carrier_signal
+        fs, fc, fm = 100000.0, 10000.0, 1000.0
+        t = np.arange(20000) / fs
+        m = np.sin(2 * np.pi * fm * t)
+
+        # Ac^2 / 2 in the carrier, (ka Ac / 2)^2 / 2 in each sideband
+        carrier, upper, lower = sideband_powers(am_modulate(m, t, fc, 1.0, 0.5), fs, fc, fm)
+        self.assertAlmostEqual(carrier, 0.5, delta=0.01)
+        self.assertAlmostEqual(upper, 0.03125, delta=0.002)
+        self.assertAlmostEqual(lower, upper, delta=0.002)
+
+        carrier, upper, lower = sideband_powers(dsbsc_modulate(m, t, fc), fs, fc, fm)
+        self.assertLess(carrier, 1e-3 * upper)
+        self.assertAlmostEqual(lower, upper, delta=0.01 * upper)
+
+        # Upper-sideband SSB of a tone is a single tone at fc + fm
+        carrier, upper, lower = sideband_powers(carrier_signal(t, fc + fm), fs, fc, fm)
+        self.assertAlmostEqual(upper, 0.5, delta=0.01)
+        self.assertLess(lower, 1e-3 * upper)
+        self.assertLess(carrier, 1e-3 * upper)
+
+        with self.assertRaises(ValueError):
+            sideband_powers(m, fs, 49000.0, fm)
+
+    def test_save_spectrum_csv_round_trip(self):
+        """Test a saved Welch spectrum reads back with its peak at the input tone."""
+        fs, tone = 8000.0, 1250.0
+        x = np.sin(2 * np.pi * tone * np.arange(8192) / fs)
+        freqs, power_db = welch_psd(x, fs, 256, 128)
+
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "spectrum.csv")
+            save_spectrum_csv(freqs, power_db, path)
+            with open(path, newline='') as f:
+                rows = list(csv.reader(f))
+
+            self.assertEqual(rows[0], ['Frequency_Hz', 'Power_dB'])
+            loaded = np.array(rows[1:], dtype=float)
+            self.assertTrue(np.array_equal(loaded[:, 0], freqs))
+            self.assertTrue(np.array_equal(loaded[:, 1], power_db))
+            self.assertAlmostEqual(loaded[np.argmax(loaded[:, 1]), 0], tone, delta=fs / 256)
+
+            with self.assertRaises(ValueError):
+                save_spectrum_csv(freqs, power_db[:-1], path)
+
+if __name__ == '__main__':
+    unittest.main()
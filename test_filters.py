@@ -0,0 +1,222 @@
+"""Unit tests for filter design and application."""
+
+import unittest
+import numpy as np
+from scipy import signal
+
+from filters import butterworth_sos, butterworth_lowpass, butterworth_bandpass, design_fir_lowpass, FIR_WINDOWS
+from filters import decimate, interpolate, resample, rc_lowpass, dc_blocker
+
+
+class TestFilters(unittest.TestCase):
+    """Test filter functions."""
+
+    def setUp(self):
+        """Set up test parameters."""
+        self.sampling_rate = 10000.0
+        self.cutoff = 500.0
+
+    def _steady_state_gain(self, freq, filter_fn):
+        t = np.arange(int(self.sampling_rate)) / self.sampling_rate
+        x = np.sin(2 * np.pi * freq * t)
+        y = filter_fn(x)
+        # Skip the start-up transient; the last half-second holds whole cycles
+        return np.sqrt(2.0 * np.mean(y[len(y) // 2:] ** 2))
+
+    def test_butterworth_matches_reference_design(self):
+        """Test that the bilinear biquad design matches scipy's Butterworth."""
+        for order in (1, 2, 3, 4, 5):
+            sos = butterworth_sos(self.cutoff, self.sampling_rate, order)
+            reference = signal.butter(order, self.cutoff, fs=self.sampling_rate, output='sos')
+            w, h = signal.sosfreqz(sos, worN=512, fs=self.sampling_rate)
+            _, h_ref = signal.sosfreqz(reference, worN=512, fs=self.sampling_rate)
+            self.assertTrue(np.allclose(np.abs(h), np.abs(h_ref), atol=1e-9))
+
+            sos = butterworth_sos(self.cutoff, self.sampling_rate, order, highpass=True)
+            reference = signal.butter(order, self.cutoff, btype='highpass', fs=self.sampling_rate, output='sos')
+            _, h = signal.sosfreqz(sos, worN=512, fs=self.sampling_rate)
+            _, h_ref = signal.sosfreqz(reference, worN=512, fs=self.sampling_rate)
+            self.assertTrue(np.allclose(np.abs(h), np.abs(h_ref), atol=1e-9))
+
+    def test_butterworth_minus_3db_point(self):
+        """Test the -3 dB point by sweeping input sinusoids."""
+        freqs = np.arange(300.0, 800.0, 10.0)
+        gains = np.array([
+            self._steady_state_gain(f, lambda x: butterworth_lowpass(x, self.sampling_rate, self.cutoff, 4))
+            for f in freqs
+        ])
+        gains_db = 20 * np.log10(gains)
+
+        # Gain falls monotonically through the band edge
+        self.assertTrue(np.all(np.diff(gains_db) < 0.05))
+        crossing = freqs[np.argmin(np.abs(gains_db + 3.0))]
+        self.assertAlmostEqual(crossing, self.cutoff, delta=20.0)
+
+        # Passband is flat, stopband is attenuated
+        passband = self._steady_state_gain(50.0, lambda x: butterworth_lowpass(x, self.sampling_rate, self.cutoff, 4))
+        stopband = self._steady_state_gain(2000.0, lambda x: butterworth_lowpass(x, self.sampling_rate, self.cutoff, 4))
+        self.assertAlmostEqual(passband, 1.0, delta=0.01)
+        self.assertLess(20 * np.log10(stopband), -40.0)
+
+    def test_zero_phase(self):
+        """Test that zero-phase filtering introduces no delay."""
+        t = np.arange(2000) / self.sampling_rate
+        x = np.sin(2 * np.pi * 100.0 * t)
+        y = butterworth_lowpass(x, self.sampling_rate, self.cutoff, 4, zero_phase=True)
+        self.assertLess(np.max(np.abs(y[200:-200] - x[200:-200])), 1e-3)
+
+    def test_bandpass_rejects_out_of_band_noise(self):
+        """Test that an in-band tone passes while out-of-band noise is removed."""
+        rng = np.random.default_rng(0)
+        t = np.arange(int(self.sampling_rate)) / self.sampling_rate
+        tone = np.sin(2 * np.pi * 2000.0 * t)
+        # Noise confined to 3.5-4.5 kHz, well outside the 1.5-2.5 kHz passband
+        noise = butterworth_bandpass(rng.standard_normal(len(t)), self.sampling_rate, 3500.0, 4500.0, 8)
+
+        def bandpass(x):
+            return butterworth_bandpass(x, self.sampling_rate, 1500.0, 2500.0, 4, zero_phase=True)
+
+        self.assertAlmostEqual(self._steady_state_gain(2000.0, bandpass), 1.0, delta=0.01)
+        # The filter is linear, so the noise that survives is bandpass(noise)
+        residual = bandpass(tone + noise) - bandpass(tone)
+        attenuation_db = 10 * np.log10(np.mean(residual[500:-500] ** 2) / np.mean(noise[500:-500] ** 2))
+        self.assertLess(attenuation_db, -30.0)
+
+        with self.assertRaises(ValueError):
+            butterworth_bandpass(tone, self.sampling_rate, 2500.0, 1500.0)
+
+    def test_fir_lowpass_unity_dc_gain(self):
+        """Test that FIR taps are centered and sum to 1.0 for every window."""
+        for window in FIR_WINDOWS:
+            fir = design_fir_lowpass(self.cutoff, self.sampling_rate, 101, window)
+            self.assertAlmostEqual(np.sum(fir.taps), 1.0, places=12)
+            self.assertTrue(np.allclose(fir.taps, fir.taps[::-1]))
+
+    def test_fir_lowpass_stopband_attenuation(self):
+        """Test that a Hamming FIR attenuates an out-of-band tone by at least 40 dB."""
+        fir = design_fir_lowpass(self.cutoff, self.sampling_rate, 101, "hamming")
+
+        passband = self._steady_state_gain(100.0, fir.apply)
+        stopband = self._steady_state_gain(2000.0, fir.apply)
+        self.assertAlmostEqual(passband, 1.0, delta=0.01)
+        self.assertLess(20 * np.log10(stopband), -40.0)
+
+        # Output stays aligned with and as long as the input
+        x = np.sin(2 * np.pi * 100.0 * np.arange(1000) / self.sampling_rate)
+        y = fir.apply(x)
+        self.assertEqual(len(y), len(x))
+        self.assertLess(np.max(np.abs(y[100:-100] - x[100:-100])), 0.01)
+        self.assertEqual(len(fir.apply(np.ones(10))), 10)
+
+        with self.assertRaises(ValueError):
+            design_fir_lowpass(self.cutoff, self.sampling_rate, 101, "kaiser")
+
+    def test_decimate_interpolate_round_trip(self):
+        """Test that decimating by 2 then interpolating by 2 preserves baseband content."""
+        t = np.arange(1000) / self.sampling_rate
+        x = np.sin(2 * np.pi * 200.0 * t) + 0.5 * np.sin(2 * np.pi * 700.0 * t)
+
+        t_dec, x_dec = decimate(x, t, 2)
+        self.assertEqual(len(x_dec), 500)
+        self.assertAlmostEqual(t_dec[1] - t_dec[0], 2.0 / self.sampling_rate, places=12)
+
+        t_int, x_int = interpolate(x_dec, t_dec, 2)
+        self.assertEqual(len(x_int), 1000)
+        self.assertTrue(np.allclose(t_int, t))
+        self.assertGreater(np.corrcoef(x, x_int)[0, 1], 0.9)
+
+    def test_decimate_rejects_aliasing_tone(self):
+        """Test that the anti-aliasing filter removes content above the new Nyquist."""
+        t = np.arange(4000) / self.sampling_rate
+        x = np.sin(2 * np.pi * 4000.0 * t)
+        _, x_dec = decimate(x, t, 4)
+        self.assertLess(np.max(np.abs(x_dec[50:-50])), 0.05)
+
+    def test_resampling_invalid_factor(self):
+        """Test factor guards."""
+        t = np.arange(10) / self.sampling_rate
+        x = np.ones(10)
+        with self.assertRaises(ValueError):
+            decimate(x, t, 0)
+        with self.assertRaises(ValueError):
+            interpolate(x, t, 0)
+        _, same = decimate(x, t, 1)
+        self.assertTrue(np.array_equal(same, x))
+
+    def test_resample_non_integer_round_trip(self):
+        """Test resampling a tone to a non-integer rate and back keeps its frequency and shape."""
+        fs, new_rate, tone = 8000.0, 11025.0, 300.0
+        t = np.arange(8000) / fs
+        x = np.sin(2 * np.pi * tone * t)
+
+        t_up, x_up = resample(x, t, new_rate)
+        self.assertEqual(len(x_up), 11025)
+        self.assertAlmostEqual(t_up[1] - t_up[0], 1.0 / new_rate, places=12)
+        freqs = np.fft.rfftfreq(len(x_up), 1.0 / new_rate)
+        self.assertAlmostEqual(freqs[np.argmax(np.abs(np.fft.rfft(x_up)))], tone, delta=1.0)
+        # The resampled points lie on the same sine
+        self.assertGreater(np.corrcoef(x_up[200:-200], np.sin(2 * np.pi * tone * t_up[200:-200]))[0, 1], 0.999)
+
+        t_back, x_back = resample(x_up, t_up, fs)
+        self.assertEqual(len(x_back), len(x))
+        self.assertTrue(np.allclose(t_back, t))
+        self.assertGreater(np.corrcoef(x[100:-100], x_back[100:-100])[0, 1], 0.999)
+
+        _, same = resample(x, t, fs)
+        self.assertTrue(np.array_equal(same, x))
+        with self.assertRaises(ValueError):
+            resample(x, t, 0.0)
+
+    def test_rc_lowpass_diagonal_clipping(self):
+        """Test an RC detector tracks the AM envelope only when RC is short next to the message period."""
+        from demod import diode_rectify
+        from signals import am_modulate
+
+        fs, fc, fm = 200000.0, 20000.0, 500.0
+        t = np.arange(int(0.1 * fs)) / fs
+        message = np.sin(2 * np.pi * fm * t)
+        rectified = diode_rectify(am_modulate(message, t, fc, 1.0, 0.5))
+
+        def tracking(rc):
+            # Skip the capacitor's initial charge
+            y = rc_lowpass(rectified, fs, rc)[4000:]
+            return np.corrcoef(message[4000:], y)[0, 1]
+
+        # Corner between message and carrier vs ten times slower than the message
+        self.assertGreater(tracking(1.0 / (2 * np.pi * np.sqrt(fc * fm))), 0.95)
+        self.assertLess(tracking(10.0 / (2 * np.pi * fm)), 0.3)
+
+        # alpha = dt / (RC + dt): a unit step charges to 1 - (1 - alpha)^n
+        step = rc_lowpass(np.ones(5), 1.0, 1.0)
+        self.assertTrue(np.allclose(step, 1.0 - 0.5 ** np.arange(1, 6)))
+        with self.assertRaises(ValueError):
+            rc_lowpass(message, fs, 0.0)
+
+    def test_dc_blocker(self):
+        """Test the DC blocker removes a constant offset and passes AC near unity gain."""
+        t = np.arange(int(self.sampling_rate)) / self.sampling_rate
+        x = 2.0 + np.sin(2 * np.pi * 1000.0 * t)
+        y = dc_blocker(x, 0.995)
+        # Start-up transient decays as 0.995^n; the last half-second holds whole cycles
+        self.assertAlmostEqual(np.mean(y[len(y) // 2:]), 0.0, places=3)
+
+        # Corner near (1 - pole) * fs / 2 pi = 8 Hz
+        for freq in (100.0, 1000.0, 4000.0):
+            with self.subTest(freq=freq):
+                self.assertAlmostEqual(self._steady_state_gain(freq, dc_blocker), 1.0, delta=0.01)
+        self.assertLess(self._steady_state_gain(1.0, dc_blocker), 0.3)
+        with self.assertRaises(ValueError):
+            dc_blocker(x, 1.0)
+
+    def test_invalid_design(self):
+        """Test invalid cutoff and order."""
+        with self.assertRaises(ValueError):
+            butterworth_sos(0.0, self.sampling_rate)
+        with self.assertRaises(ValueError):
+            butterworth_sos(self.sampling_rate, self.sampling_rate)
+        with self.assertRaises(ValueError):
+            butterworth_sos(self.cutoff, self.sampling_rate, 0)
+
+
+if __name__ == '__main__':
+    unittest.main()
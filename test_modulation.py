@@ -0,0 +1,139 @@
+"""Unit tests for the modulate/demodulate dispatcher."""
+
+import unittest
+import numpy as np
+
+from config import SimulationParams
+from signals import generate_time_vector, message_signal, AM_VARIANTS, am_carrier_level
+import modulation
+from config import check_params
+from modulation import MODULATION_TYPES, modulate, demodulate, register_modulation, registered_modulations
+from modulation import demodulate_and_decimate
+from noise import add_noise
+from utils import calculate_output_snr_aligned, run_monte_carlo_simulation
+
+
+class TestModulation(unittest.TestCase):
+    """Test modulation dispatch across all registered types."""
+    
+    def setUp(self):
+        """Set up test parameters."""
+        self.params = SimulationParams(sampling_rate=100000.0, carrier_freq=10000.0, message_freq=1000.0,
+                                       duration=0.05, fm_deviation=2000.0)
+        self.t = generate_time_vector(self.params.sampling_rate, self.params.duration)
+        self.message = message_signal(self.t, self.params.message_freq, self.params.message_amplitude)
+    
+    def tearDown(self):
+        """Drop schemes registered by a test."""
+        modulation._REGISTRY.pop("identity", None)
+    
+    def test_round_trip_all_types(self):
+        """Test every registered type gives non-empty output that tracks the message."""
+        for mod_type in MODULATION_TYPES:
+            with self.subTest(mod_type=mod_type):
+                modulated = modulate(mod_type, self.message, self.t, self.params)
+                self.assertEqual(len(modulated), len(self.t))
+                self.assertTrue(np.all(np.isfinite(modulated)))
+                demodulated = demodulate(mod_type, modulated, self.t, self.params)
+                self.assertGreater(len(demodulated), 0)
+                snr = calculate_output_snr_aligned(self.message, demodulated, self.params.sampling_rate,
+                                                   self.params.message_freq)
+                self.assertGreater(snr, 10.0)
+    
+    def test_fm_limiter_rejects_amplitude_noise(self):
+        """Test that the band-pass limiter improves FM recovery under amplitude noise."""
+        rng = np.random.default_rng(4)
+        fm_signal = modulate("fm", self.message, self.t, self.params)
+        corrupted = fm_signal * (1.0 + 0.4 * rng.standard_normal(len(fm_signal)))
+        
+        def correlation(limiter):
+            self.params.fm_limiter = limiter
+            demodulated = demodulate("fm", corrupted, self.t, self.params)
+            # Skip the filter edges
+            return np.corrcoef(self.message[500:-500], demodulated[500:-500])[0, 1]
+        
+        plain = correlation(False)
+        limited = correlation(True)
+        self.assertGreater(limited, plain)
+        self.assertGreater(limited, 0.95)
+    
+    def test_demodulate_and_decimate(self):
+        """Test decimated output is shorter by the factor and no noisier than the full-rate output."""
+        for mod_type in ("am", "fm"):
+            with self.subTest(mod_type=mod_type):
+                noisy = add_noise(modulate(mod_type, self.message, self.t, self.params), 10.0, seed=7)
+                full = demodulate(mod_type, noisy, self.t, self.params)
+                t_dec, decimated = demodulate_and_decimate(mod_type, noisy, self.t, self.params, 10000.0)
+                self.assertEqual(len(decimated), int(np.ceil(len(full) / 10)))
+                self.assertAlmostEqual(t_dec[1] - t_dec[0], 10 / self.params.sampling_rate)
+                
+                full_snr = calculate_output_snr_aligned(self.message, full, self.params.sampling_rate,
+                                                        self.params.message_freq)
+                decimated_snr = calculate_output_snr_aligned(self.message[::10], decimated,
+                                                             self.params.sampling_rate / 10,
+                                                             self.params.message_freq)
+                self.assertGreaterEqual(decimated_snr, full_snr - 0.5)
+        
+        with self.assertRaises(ValueError):
+            demodulate_and_decimate("am", noisy, self.t, self.params, 1500.0)
+    
+    def test_am_variants(self):
+        """Test carrier power per AM variant and that each one's receiver recovers the message."""
+        from fft import sideband_powers
+        from config import am_receiver
+        carrier_powers = {}
+        for variant in AM_VARIANTS:
+            with self.subTest(variant=variant):
+                self.params.am_variant = variant
+                modulated = modulate("am", self.message, self.t, self.params)
+                carrier_powers[variant], upper, lower = sideband_powers(
+                    modulated, self.params.sampling_rate, self.params.carrier_freq, self.params.message_freq)
+                # The sidebands carry ka * Am / 2 whatever the carrier
+                self.assertAlmostEqual(upper, (self.params.am_index / 2) ** 2 / 2, delta=0.005)
+                
+                demodulated = demodulate("am", add_noise(modulated, 20.0, seed=3), self.t, self.params)
+                snr = calculate_output_snr_aligned(self.message, demodulated, self.params.sampling_rate,
+                                                   self.params.message_freq)
+                self.assertGreater(snr, 10.0)
+        
+        self.assertAlmostEqual(carrier_powers["full-carrier"], 0.5, delta=0.01)
+        self.assertLess(carrier_powers["suppressed-carrier"], 1e-4)
+        self.assertAlmostEqual(carrier_powers["reduced-carrier"], self.params.am_pilot_level ** 2 / 2, delta=0.002)
+        self.assertEqual(am_receiver(self.params), "coherent")
+        self.params.am_variant = "full-carrier"
+        self.assertEqual(am_receiver(self.params), "envelope")
+        with self.assertRaises(ValueError):
+            am_carrier_level("vestigial")
+    
+    def test_unknown_type(self):
+        """Test unknown modulation types are rejected."""
+        with self.assertRaises(ValueError):
+            modulate("ssb", self.message, self.t, self.params)
+        with self.assertRaises(ValueError):
+            demodulate("qpsk", self.message, self.t, self.params)
+    
+    def test_register_custom_scheme(self):
+        """Test a registered identity scheme runs through the Monte Carlo path."""
+        self.assertEqual(registered_modulations()[:len(MODULATION_TYPES)], MODULATION_TYPES)
+        with self.assertRaises(ValueError):
+            check_params(SimulationParams(extra_modulations=["identity"]))
+        
+        register_modulation("identity", lambda m, t, p: m.copy(), lambda r, t, p: r)
+        self.assertIn("identity", registered_modulations())
+        self.assertTrue(np.array_equal(modulate("identity", self.message, self.t, self.params), self.message))
+        with self.assertRaises(TypeError):
+            register_modulation("broken", None, None)
+        
+        self.params.extra_modulations = ["identity"]
+        self.params.snr_values = [0.0, 20.0]
+        self.params.trials = 3
+        results = run_monte_carlo_simulation(self.params)
+        means = results.extra_means["identity"]
+        self.assertEqual(sorted(means), [0.0, 20.0])
+        self.assertEqual(len(results.extra_results["identity"][20.0]), 3)
+        self.assertTrue(np.all(np.isfinite(list(means.values()))))
+        self.assertGreater(means[20.0], means[0.0] + 10.0)
+
+
+if __name__ == '__main__':
+    unittest.main()
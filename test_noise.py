@@ -2,8 +2,13 @@
 
 import unittest
 import numpy as np
+from scipy import signal as sps
 
 from noise import add_gaussian_noise, calculate_signal_power, calculate_noise_power, calculate_snr_db
+from noise import add_colored_noise, add_impulse_noise, RayleighChannel, add_noise, NOISE_DISTRIBUTIONS
+from noise import quantize, phase_noise, add_phase_noise, add_timing_jitter
+from noise import add_awgn_ebn0, ebn0_to_snr_db
+from fft import welch_psd
 
 
 class TestNoiseFunctions(unittest.TestCase):
@@ -97,6 +102,119 @@ class TestNoiseFunctions(unittest.TestCase):
             # Should be close to requested SNR (allow for some variance)
             self.assertAlmostEqual(actual_snr_db, snr_db, delta=2.0)
     
+    def test_noise_distributions(self):
+        """Test each distribution hits the target SNR and has its expected kurtosis."""
+        signal = np.sin(2 * np.pi * 0.01 * np.arange(100000))
+        kurtosis = {}
+        for distribution in NOISE_DISTRIBUTIONS:
+            with self.subTest(distribution=distribution):
+                noise = add_noise(signal, self.snr_db, distribution, seed=7) - signal
+                achieved = calculate_snr_db(calculate_signal_power(signal), calculate_signal_power(noise))
+                self.assertAlmostEqual(achieved, self.snr_db, delta=0.1)
+                self.assertAlmostEqual(np.mean(noise), 0.0, delta=0.01)
+                kurtosis[distribution] = np.mean(noise ** 4) / np.mean(noise ** 2) ** 2
+        
+        # Theoretical kurtosis: uniform 1.8, Gaussian 3, Laplacian 6
+        self.assertAlmostEqual(kurtosis["uniform"], 1.8, delta=0.1)
+        self.assertAlmostEqual(kurtosis["gaussian"], 3.0, delta=0.2)
+        self.assertAlmostEqual(kurtosis["laplacian"], 6.0, delta=0.6)
+        self.assertGreater(kurtosis["laplacian"], kurtosis["gaussian"])
+        self.assertGreater(kurtosis["gaussian"], kurtosis["uniform"])
+        
+        # Gaussian is the existing generator, seed for seed
+        self.assertTrue(np.array_equal(add_noise(signal, 5.0, "gaussian", seed=3),
+                                       add_gaussian_noise(signal, 5.0, seed=3)))
+        with self.assertRaises(ValueError):
+            add_noise(signal, 5.0, "cauchy")
+    
+    def test_ebn0_noise(self):
+        """Test the Eb/N0 to noise variance conversion against SNR = Eb/N0 * 2Rb/fs."""
+        from utils import snr_to_ebn0_db
+        
+        fs, bit_rate = 100000.0, 1000.0
+        # 2Rb/fs = 1/50, i.e. -16.99 dB
+        self.assertAlmostEqual(ebn0_to_snr_db(10.0, bit_rate, fs), 10.0 - 10.0 * np.log10(50.0))
+        self.assertAlmostEqual(ebn0_to_snr_db(0.0, 1000.0, 2000.0), 0.0)
+        self.assertAlmostEqual(snr_to_ebn0_db(ebn0_to_snr_db(7.0, bit_rate, fs), fs, bit_rate), 7.0)
+        
+        signal = np.sin(2 * np.pi * 0.01 * np.arange(200000))
+        noise = add_awgn_ebn0(signal, 10.0, bit_rate, fs, seed=1) - signal
+        # Eb = P / Rb and N0 = sigma^2 / (fs / 2)
+        eb = calculate_signal_power(signal) / bit_rate
+        n0 = calculate_signal_power(noise) / (fs / 2.0)
+        self.assertAlmostEqual(10.0 * np.log10(eb / n0), 10.0, delta=0.1)
+        with self.assertRaises(ValueError):
+            ebn0_to_snr_db(10.0, 0.0, fs)
+    
+    def test_colored_noise_psd_slope(self):
+        """Test that the noise PSD falls as 1/f^exponent and the SNR is exact."""
+        signal = np.sin(2 * np.pi * 0.01 * np.arange(2 ** 16))
+        
+        for exponent in (0.0, 1.0, 2.0):
+            noisy = add_colored_noise(signal, self.snr_db, exponent=exponent, seed=7)
+            noise = noisy - signal
+            
+            actual_snr_db = calculate_snr_db(calculate_signal_power(signal), calculate_signal_power(noise))
+            self.assertAlmostEqual(actual_snr_db, self.snr_db, places=9)
+            
+            # Fit log-power against log-frequency away from DC and Nyquist
+            freqs, power_db = welch_psd(noise, 1.0, segment_length=1024, overlap=512)
+            band = slice(4, 256)
+            slope = np.polyfit(np.log10(freqs[band]), power_db[band] / 10.0, 1)[0]
+            self.assertAlmostEqual(slope, -exponent, delta=0.1)
+    
+    def test_impulse_noise(self):
+        """Test impulse noise at the probability extremes and the range guard."""
+        unchanged = add_impulse_noise(self.test_signal, 0.0, 10.0, seed=1)
+        self.assertTrue(np.array_equal(unchanged, self.test_signal))
+        
+        perturbed = add_impulse_noise(self.test_signal, 1.0, 10.0, seed=1)
+        self.assertTrue(np.allclose(np.abs(perturbed - self.test_signal), 10.0))
+        
+        # Spikes take both signs
+        spikes = perturbed - self.test_signal
+        self.assertTrue(np.any(spikes > 0) and np.any(spikes < 0))
+        
+        with self.assertRaises(ValueError):
+            add_impulse_noise(self.test_signal, -0.1, 1.0)
+        with self.assertRaises(ValueError):
+            add_impulse_noise(self.test_signal, 1.5, 1.0)
+    
+    def test_rayleigh_envelope_statistics(self):
+        """Test that the fading magnitude follows a unit-power Rayleigh distribution."""
+        magnitudes = np.concatenate([
+            np.abs(RayleighChannel(50.0, 2000.0, seed=seed).gains(20000))
+            for seed in range(20)
+        ])
+        
+        self.assertAlmostEqual(np.mean(magnitudes ** 2), 1.0, delta=0.1)
+        # Rayleigh with E[r^2] = 1 has mean sqrt(pi)/2 and CDF 1 - exp(-r^2)
+        self.assertAlmostEqual(np.mean(magnitudes) / np.sqrt(np.mean(magnitudes ** 2)),
+                               np.sqrt(np.pi) / 2.0, delta=0.03)
+        for r in (0.25, 0.5, 1.0, 1.5):
+            self.assertAlmostEqual(np.mean(magnitudes < r), 1.0 - np.exp(-r ** 2), delta=0.05)
+    
+    def test_rayleigh_channel_apply(self):
+        """Test that fading preserves length and is reproducible."""
+        t = np.arange(4000) / 10000.0
+        x = np.sin(2 * np.pi * 1000.0 * t)
+        faded1 = RayleighChannel(20.0, 10000.0, seed=3).apply(x)
+        faded2 = RayleighChannel(20.0, 10000.0, seed=3).apply(x)
+        
+        self.assertEqual(len(faded1), len(x))
+        self.assertTrue(np.array_equal(faded1, faded2))
+        self.assertFalse(np.allclose(faded1, x))
+        
+        with self.assertRaises(ValueError):
+            RayleighChannel(-1.0, 10000.0)
+    
+    def test_colored_noise_reproducibility(self):
+        """Test that colored noise is reproducible with same seed."""
+        noisy1 = add_colored_noise(self.test_signal, self.snr_db, exponent=1.0, seed=123)
+        noisy2 = add_colored_noise(self.test_signal, self.snr_db, exponent=1.0, seed=123)
+        self.assertTrue(np.allclose(noisy1, noisy2))
+        self.assertEqual(noisy1.shape, self.test_signal.shape)
+    
     def test_edge_cases(self):
         """Test edge cases."""
         # Zero signal
@@ -115,7 +233,68 @@ class TestNoiseFunctions(unittest.TestCase):
         noise_power = calculate_signal_power(low_snr_signal - self.test_signal)
         signal_power = calculate_signal_power(self.test_signal)
         self.assertGreater(noise_power, signal_power)
-
+    
+    def test_quantization_snr(self):
+        """Test a full-scale sine quantizes to ~6.02 N + 1.76 dB SNR and out-of-range values saturate."""
+        n = np.arange(100000)
+        sine = np.sin(2 * np.pi * 0.0123457 * n)
+        for bits in (6, 8, 12):
+            with self.subTest(bits=bits):
+                quantized = quantize(sine, bits, 1.0)
+                self.assertLessEqual(len(np.unique(quantized)), 2 ** bits)
+                measured = calculate_snr_db(calculate_signal_power(sine), calculate_noise_power(sine, quantized))
+                self.assertAlmostEqual(measured, 6.02 * bits + 1.76, delta=1.0)
+        
+        step = 2.0 / 2 ** 8
+        saturated = quantize(np.array([-5.0, -1.0, 1.0, 5.0]), 8, 1.0)
+        self.assertTrue(np.allclose(saturated, [-1 + step / 2, -1 + step / 2, 1 - step / 2, 1 - step / 2]))
+        with self.assertRaises(ValueError):
+            quantize(sine, 0, 1.0)
+    
+    def test_phase_noise_rms_and_line_broadening(self):
+        """Test that phase noise has the requested RMS and spreads the carrier line."""
+        fs = 20000.0
+        t = np.arange(20000) / fs
+        carrier = np.cos(2 * np.pi * 1000.0 * t)
+        for rms in (0.05, 0.3, 1.0):
+            with self.subTest(rms=rms):
+                phi = phase_noise(len(t), rms, fs, seed=7)
+                self.assertAlmostEqual(np.sqrt(np.mean(phi ** 2)), rms, places=9)
+        
+                noisy = add_phase_noise(carrier, rms, fs, seed=7)
+                # Away from the Hilbert edge effects the phase error is phi itself
+                error = np.angle(np.exp(1j * (np.unwrap(np.angle(sps.hilbert(noisy)))
+                                              - np.unwrap(np.angle(sps.hilbert(carrier))))))
+                self.assertAlmostEqual(np.sqrt(np.mean(error[500:-500] ** 2)), rms, delta=0.1 * rms)
+        
+        def line_width(x):
+            # Bins within 20 dB of the spectral peak
+            spectrum = np.abs(np.fft.rfft(x)) ** 2
+            return int(np.sum(spectrum > spectrum.max() / 100.0))
+        
+        self.assertEqual(line_width(carrier), 1)
+        self.assertGreater(line_width(add_phase_noise(carrier, 1.0, fs, seed=7)), 10)
+        self.assertTrue(np.allclose(add_phase_noise(carrier, 0.0, fs, seed=7), carrier, atol=1e-9))
+        with self.assertRaises(ValueError):
+            phase_noise(100, -0.1, fs)
+    
+    def test_timing_jitter_hurts_high_frequencies_more(self):
+        """Test that clock jitter error scales with the tone frequency (about 2π f σ)."""
+        fs, sigma = 100000.0, 1e-6
+        t = np.arange(50000) / fs
+        errors = {}
+        for freq in (100.0, 10000.0):
+            tone = np.sin(2 * np.pi * freq * t)
+            jittered = add_timing_jitter(tone, sigma, fs, seed=11)
+            self.assertEqual(len(jittered), len(tone))
+            errors[freq] = np.sqrt(np.mean((jittered - tone) ** 2))
+        
+        # Slow tone: interpolation is exact enough that only the jitter term remains
+        self.assertAlmostEqual(errors[100.0], 2 * np.pi * 100.0 * sigma / np.sqrt(2), delta=0.2 * errors[100.0])
+        self.assertGreater(errors[10000.0], 10 * errors[100.0])
+        self.assertTrue(np.array_equal(add_timing_jitter(tone, 0.0, fs), tone))
+        with self.assertRaises(ValueError):
+            add_timing_jitter(tone, -1e-6, fs)
 
 if __name__ == '__main__':
     unittest.main()
@@ -0,0 +1,316 @@
+"""Unit tests for plotting functions."""
+
+import unittest
+import tempfile
+import os
+
+import numpy as np
+import matplotlib
+matplotlib.use("Agg")
+
+from filters import butterworth_lowpass
+from plots import plot_performance_dashboard, plot_eye_diagram, plot_constellation, plot_snr_histogram
+from plots import set_save_format, plot_signal_overlay, plot_ber_curve, plot_noisy_vs_original
+from plots import DARK_THEME, DEFAULT_THEME, set_plot_theme, plot_snr_comparison, PlotLayout, plot_waterfall
+from plots import plot_heatmap, render_figure
+from utils import PerformanceResults, SNRMeasurement, eye_opening, cluster_variance, nearest_constellation_points
+
+# Reference renders for the golden-image tests; set UPDATE_GOLDEN=1 (or pass
+# --update-golden to run_tests.py) to rewrite them after an intended change
+GOLDEN_DIR = os.path.join(os.path.dirname(os.path.abspath(__file__)), "golden")
+
+
+class TestPlots(unittest.TestCase):
+    """Test plotting functions."""
+
+    def setUp(self):
+        """Set up a small results set."""
+        self.results = PerformanceResults(
+            snr_levels=[0.0, 5.0, 10.0],
+            am_results={0.0: [1.0, 2.0], 5.0: [3.0, 4.0], 10.0: [5.0, 6.0]},
+            fm_results={0.0: [1.5, 2.5], 5.0: [3.5, 4.5], 10.0: [5.5, 6.5]},
+            am_means={0.0: 1.5, 5.0: 3.5, 10.0: 5.5},
+            fm_means={0.0: 2.0, 5.0: 4.0, 10.0: 6.0},
+            am_stds={0.0: 0.5, 5.0: 0.5, 10.0: 0.5},
+            fm_stds={0.0: 0.5, 5.0: 0.5, 10.0: 0.5}
+        )
+
+    def test_performance_dashboard_written(self):
+        """Test that the dashboard image is created and non-trivial."""
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "dashboard.png")
+            plot_performance_dashboard(self.results, path, width=8.0, height=6.0, dpi=80)
+
+            self.assertTrue(os.path.exists(path))
+            self.assertGreater(os.path.getsize(path), 5 * 1024)
+
+
+    def test_noisy_vs_original_written(self):
+        """Test the noisy-vs-original grid (part of generate_all_plots) renders and saves."""
+        import matplotlib.pyplot as plt
+        from config import SimulationParams
+        params = SimulationParams(duration=0.01)
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "noisy_vs_original.png")
+            plot_noisy_vs_original(params, 10.0, path)
+            self.assertTrue(os.path.exists(path))
+        self.assertEqual(len(plt.gcf().axes), 4)
+        plt.close('all')
+
+
+    def assert_matches_golden(self, name, pixels, tolerance=0.005):
+        """Compare an RGBA render to golden/<name>.png, allowing a small fraction of changed pixels."""
+        import matplotlib.pyplot as plt
+        path = os.path.join(GOLDEN_DIR, name + ".png")
+        if os.environ.get("UPDATE_GOLDEN") == "1":
+            os.makedirs(GOLDEN_DIR, exist_ok=True)
+            plt.imsave(path, pixels)
+            return
+        self.assertTrue(os.path.exists(path),
+                        f"missing golden image {path}; create it with run_tests.py --update-golden and commit it")
+        golden = np.round(plt.imread(path) * 255).astype(np.uint8)
+        self.assertEqual(golden.shape, pixels.shape, f"{name} render size changed")
+        # Antialiasing can shift a few edge pixels by a level or two
+        changed = np.any(np.abs(golden.astype(int) - pixels.astype(int)) > 8, axis=-1)
+        self.assertLessEqual(np.mean(changed), tolerance,
+                             f"{name} differs from its golden image in {np.mean(changed):.2%} of pixels")
+
+
+    def test_snr_comparison_golden(self):
+        """Test the SNR comparison plot still renders the same image."""
+        import matplotlib.pyplot as plt
+        set_plot_theme(DEFAULT_THEME)
+        plot_snr_comparison(self.results)
+        pixels = render_figure(dpi=80)
+        plt.close('all')
+
+        self.assertEqual(pixels.shape, (480, 800, 4))
+        self.assert_matches_golden("snr_comparison", pixels)
+
+
+    def test_eye_diagram(self):
+        """Test that the eye plot is written and noise closes the eye."""
+        sps = 16
+        rng = np.random.default_rng(0)
+        bits = rng.integers(0, 2, 200)
+        # Band-limited NRZ, plus a partial trailing symbol to exercise truncation
+        nrz = np.repeat(2.0 * bits - 1.0, sps)
+        clean = butterworth_lowpass(np.concatenate([nrz, nrz[:5]]), float(sps), 0.8, 4, zero_phase=True)
+        noisy = clean + 0.3 * rng.standard_normal(len(clean))
+
+        self.assertGreater(eye_opening(clean, sps), eye_opening(noisy, sps))
+        self.assertGreater(eye_opening(clean, sps), 1.0)
+
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "eye.png")
+            plot_eye_diagram(noisy, sps, path)
+            self.assertTrue(os.path.exists(path))
+            self.assertGreater(os.path.getsize(path), 0)
+
+
+    def test_constellation(self):
+        """Test QPSK clusters, the scatter metric and the plot file."""
+        rng = np.random.default_rng(1)
+        reference = np.array([1 + 1j, -1 + 1j, -1 - 1j, 1 - 1j]) / np.sqrt(2)
+        symbols = reference[rng.integers(0, 4, 400)]
+        clean = symbols + 0.05 * (rng.standard_normal(400) + 1j * rng.standard_normal(400))
+        noisy = symbols + 0.25 * (rng.standard_normal(400) + 1j * rng.standard_normal(400))
+
+        # Four well-separated clusters: every ideal point collects samples
+        clusters = nearest_constellation_points(clean.real, clean.imag, reference)
+        self.assertEqual(len(np.unique(clusters)), 4)
+        self.assertTrue(np.all(reference[clusters] == symbols))
+
+        self.assertGreater(cluster_variance(noisy.real, noisy.imag, reference),
+                           cluster_variance(clean.real, clean.imag, reference))
+
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "constellation.png")
+            plot_constellation(clean.real, clean.imag, path, reference)
+            self.assertTrue(os.path.exists(path))
+            with self.assertRaises(ValueError):
+                plot_constellation(clean.real, clean.imag[:10])
+
+
+    def test_snr_histogram(self):
+        """Test that a bimodal spread fills two separated bin regions."""
+        values = np.concatenate([np.linspace(4.5, 5.5, 100), np.linspace(14.5, 15.5, 100)])
+        measurements = [SNRMeasurement(10.0, v, 'AM', k) for k, v in enumerate(values)]
+        # A different input SNR must be filtered out
+        measurements.append(SNRMeasurement(20.0, 40.0, 'AM', 0))
+
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "histogram.png")
+            counts = plot_snr_histogram(measurements, 10.0, 20, path)
+            self.assertTrue(os.path.exists(path))
+
+        self.assertEqual(len(counts), 20)
+        self.assertEqual(counts.sum(), 200)
+        populated = counts > 0
+        # Two runs of populated bins separated by empty ones
+        self.assertTrue(populated[0] and populated[-1])
+        self.assertEqual(int(np.sum(np.diff(populated.astype(int)) != 0)), 2)
+
+        with self.assertRaises(ValueError):
+            plot_snr_histogram(measurements, 10.0, 0)
+
+
+
+    def test_svg_export(self):
+        """Test that "both" writes a PNG and a valid SVG with the same basename."""
+        self.addCleanup(set_save_format, "png")
+        set_save_format("both")
+        with tempfile.TemporaryDirectory() as tmp:
+            plot_performance_dashboard(self.results, os.path.join(tmp, "dashboard.png"), width=8.0, height=6.0, dpi=80)
+            self.assertEqual(sorted(os.listdir(tmp)), ["dashboard.png", "dashboard.svg"])
+            with open(os.path.join(tmp, "dashboard.svg")) as f:
+                head = f.read(200).lstrip()
+            self.assertTrue(head.startswith("<?xml") or head.startswith("<svg"))
+
+            set_save_format("svg")
+            plot_eye_diagram(np.sin(np.linspace(0.0, 20.0, 400)), 16, os.path.join(tmp, "eye.png"))
+            self.assertIn("eye.svg", os.listdir(tmp))
+            self.assertNotIn("eye.png", os.listdir(tmp))
+
+        with self.assertRaises(ValueError):
+            set_save_format("pdf")
+
+
+    def test_dark_theme(self):
+        """Test that the dark theme fills the background and colors the AM/FM curves."""
+        import matplotlib.pyplot as plt
+        from matplotlib.colors import to_rgba
+        self.addCleanup(set_plot_theme, DEFAULT_THEME)
+        set_plot_theme("dark")
+
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "snr.png")
+            plot_snr_comparison(self.results, path)
+            image = plt.imread(path)
+        ax = plt.gcf().axes[0]
+        self.assertEqual(to_rgba(ax.get_facecolor()), to_rgba(DARK_THEME.background))
+        colors = {line.get_label(): to_rgba(line.get_color()) for line in ax.get_lines()}
+        self.assertIn(to_rgba(DARK_THEME.am_color), colors.values())
+        self.assertIn(to_rgba(DARK_THEME.fm_color), colors.values())
+        plt.close('all')
+        # The saved corners are the dark background, not white
+        self.assertLess(np.mean(image[0, 0, :3]), 0.2)
+
+        with self.assertRaises(ValueError):
+            set_plot_theme("neon")
+
+
+    def test_plot_layout(self):
+        """Test explicit limits clamp the axes, NaN ends auto-scale and the legend moves."""
+        import matplotlib.pyplot as plt
+        plot_snr_comparison(self.results, layout=PlotLayout(y_min=-5.0, y_max=3.0, legend="lower right"))
+        ax = plt.gcf().axes[0]
+        self.assertEqual(ax.get_ylim(), (-5.0, 3.0))
+        # x auto-scales around the 0..10 dB input range
+        x_low, x_high = ax.get_xlim()
+        self.assertLessEqual(x_low, 0.0)
+        self.assertGreaterEqual(x_high, 10.0)
+        self.assertIsNotNone(ax.get_legend())
+        plt.close('all')
+
+        plot_performance_dashboard(self.results, width=8.0, height=6.0, dpi=80,
+                                   advantage_layout=PlotLayout(y_min=-1.0, legend="outside"))
+        advantage_ax = plt.gcf().axes[1]
+        self.assertEqual(advantage_ax.get_ylim()[0], -1.0)
+        self.assertGreater(advantage_ax.get_ylim()[1], 0.5)
+        plt.close('all')
+
+        with self.assertRaises(ValueError):
+            plot_snr_comparison(self.results, layout=PlotLayout(legend="middle"))
+        plt.close('all')
+
+
+    def test_waterfall(self):
+        """Test the waterfall file is written with one trace per spectrogram time slice."""
+        import matplotlib.pyplot as plt
+        from fft import spectrogram, welch_psd
+        from signals import generate_time_vector, message_signal, fm_modulate
+        t = generate_time_vector(100000.0, 0.004)
+        fm = fm_modulate(message_signal(t, 1000.0), t, 10000.0, 1.0, 5000.0, 100000.0)
+        times, freqs, power_db = spectrogram(fm, 100000.0, 64, 48)
+        self.assertEqual(power_db.shape, (len(times), len(freqs)))
+        self.assertEqual(len(times), 1 + (len(fm) - 64) // 16)
+
+        # The carrier swings by the deviation between slices; Welch is their mean
+        peaks = freqs[np.argmax(power_db, axis=1)]
+        self.assertGreater(np.ptp(peaks), 5000.0)
+        _, welch_db = welch_psd(fm, 100000.0, 64, 48)
+        self.assertTrue(np.allclose(10 ** (welch_db / 10), np.mean(10 ** (power_db / 10), axis=0)))
+
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "waterfall.png")
+            plot_waterfall(times, freqs, power_db, path)
+            self.assertTrue(os.path.exists(path))
+            self.assertGreater(os.path.getsize(path), 0)
+        self.assertEqual(len(plt.gcf().axes[0].get_lines()), len(times))
+        plt.close('all')
+
+        with self.assertRaises(ValueError):
+            plot_waterfall(times, freqs, power_db[:-1])
+
+
+    def test_heatmap(self):
+        """Test the heatmap image is written with input SNR on x and index on y."""
+        import matplotlib.pyplot as plt
+        matrix = np.array([[1.0, 2.0], [3.0, 4.0], [5.0, 6.0]])
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "heatmap.png")
+            plot_heatmap(matrix, [0.0, 10.0, 20.0], [0.3, 0.6], path)
+            self.assertTrue(os.path.exists(path))
+        image = plt.gcf().axes[0].get_images()[0]
+        self.assertEqual(image.get_array().shape, (2, 3))
+        plt.close('all')
+
+        with self.assertRaises(ValueError):
+            plot_heatmap(matrix.T, [0.0, 10.0, 20.0], [0.3, 0.6])
+
+
+    def test_signal_overlay_subsampled(self):
+        """Test that a 100k-sample overlay draws at most max_points vertices per line."""
+        import matplotlib.pyplot as plt
+        t = np.arange(100000) / 100000.0
+        clean = np.sin(2 * np.pi * 50.0 * t)
+        noisy = clean + 0.1 * np.random.default_rng(2).standard_normal(len(t))
+
+        with tempfile.TemporaryDirectory() as tmp:
+            plot_signal_overlay(t, {'clean': clean, 'noisy': noisy}, os.path.join(tmp, "overlay.png"), max_points=2000)
+        lines = plt.gcf().axes[0].get_lines()
+        self.assertEqual(len(lines), 2)
+        for line in lines:
+            self.assertLessEqual(len(line.get_xdata()), 2000)
+            # The time axis still spans the whole signal
+            self.assertAlmostEqual(line.get_xdata()[0], 0.0)
+            self.assertGreater(line.get_xdata()[-1], 0.99)
+        plt.close('all')
+
+        with self.assertRaises(ValueError):
+            plot_signal_overlay(t, {'short': clean[:10]})
+
+
+    def test_ber_curve(self):
+        """Test the BER curve uses a log axis, falls with SNR and floors zero BER."""
+        import matplotlib.pyplot as plt
+        snr = [0.0, 2.0, 4.0, 6.0, 8.0, 10.0]
+        ber = [1e-1, 3e-2, 5e-3, 4e-4, 1e-5, 0.0]
+
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "ber.png")
+            plot_ber_curve(snr, ber, path, floor=1e-7)
+            self.assertTrue(os.path.exists(path))
+        ax = plt.gcf().axes[0]
+        self.assertEqual(ax.get_yscale(), 'log')
+        ydata = ax.get_lines()[0].get_ydata()
+        self.assertTrue(np.all(np.diff(ydata) < 0))
+        self.assertEqual(ydata[-1], 1e-7)
+        plt.close('all')
+
+        with self.assertRaises(ValueError):
+            plot_ber_curve(snr, ber[:3])
+
+if __name__ == '__main__':
+    unittest.main()
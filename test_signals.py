@@ -1,9 +1,16 @@
 """Unit tests for signal generation and modulation functions."""
 
+import os
+import tempfile
 import unittest
+import wave
 import numpy as np
 
 from signals import generate_time_vector, message_signal, carrier_signal, am_modulate, fm_modulate
+from signals import dsbsc_modulate, fsk_modulate, integrate_message, load_signal_csv, load_signal_wav, pm_modulate
+from signals import qam_constellation, qam_map_bits, qam_demap_symbols, qam_modulate, am_modulate_clamped
+from signals import save_signals_csv, multitone_signal, chirp_signal, square_wave_signal, arbitrary_signal
+from signals import add_signals, multiply_signals, scale_signal, mix_signals, rotation_sine, Oscillator, normalize_signal, fm_sensitivity
 
 
 class TestSignalGeneration(unittest.TestCase):
@@ -63,6 +70,32 @@ class TestSignalGeneration(unittest.TestCase):
         peak_freq = freqs[np.argmax(np.abs(spectrum))]
         self.assertAlmostEqual(peak_freq, self.carrier_freq, delta=200.0)
     
+    def test_rotation_sine_long_signal_accuracy(self):
+        """Test the rotation recurrence matches direct np.sin within 1e-6 over a long signal."""
+        num_samples = 2_000_000
+        n = np.arange(num_samples)
+        for freq, phase, block in ((1234.5, 0.0, 1024), (3000.0, 0.7, 1000)):
+            with self.subTest(freq=freq, block=block):
+                direct = 2.0 * np.sin(2.0 * np.pi * freq * n / self.sampling_rate + phase)
+                recurrence = rotation_sine(num_samples, freq, self.sampling_rate, 2.0, phase, block)
+                self.assertEqual(len(recurrence), num_samples)
+                self.assertLess(np.max(np.abs(recurrence - direct)), 1e-6)
+        self.assertEqual(len(rotation_sine(0, 100.0, self.sampling_rate)), 0)
+    
+    def test_oscillator_matches_sin(self):
+        """Test the rotation oscillator tracks direct sin generation over 100k samples."""
+        t = generate_time_vector(self.sampling_rate, 10.0)
+        osc = Oscillator(self.carrier_freq, 2.0, self.sampling_rate, phase=0.3)
+        x = osc.generate(len(t))
+        self.assertEqual(len(x), 100000)
+        reference = carrier_signal(t, self.carrier_freq, 2.0, 0.3)
+        self.assertLess(np.max(np.abs(x - reference)), 1e-5)
+        # Continues where generate stopped
+        self.assertAlmostEqual(osc.next(), 2.0 * np.sin(2 * np.pi * self.carrier_freq * 10.0 + 0.3), places=5)
+        
+        with self.assertRaises(ValueError):
+            Oscillator(1000.0, 1.0, 0.0)
+    
     def test_am_modulation(self):
         """Test AM modulation."""
         t = generate_time_vector(self.sampling_rate, self.duration)
@@ -77,6 +110,30 @@ class TestSignalGeneration(unittest.TestCase):
         correlation = np.corrcoef(envelope, expected_envelope)[0, 1]
         self.assertGreater(correlation, 0.5)
     
+    def test_am_overmodulation_clamping(self):
+        """Test that clamping at ka=1.5 leaves no negative envelope and counts the clipped samples."""
+        from config import SimulationParams, is_overmodulated
+        
+        t = generate_time_vector(self.sampling_rate, self.duration)
+        message = message_signal(t, self.message_freq, self.amplitude)
+        carrier = np.sin(2 * np.pi * self.carrier_freq * t)
+        
+        # Unclamped, the carrier phase reverses wherever 1 + ka*m < 0
+        plain = am_modulate(message, t, self.carrier_freq, self.amplitude, 1.5)
+        self.assertTrue(np.any(plain * carrier < -1e-9))
+        
+        clamped, count = am_modulate_clamped(message, t, self.carrier_freq, self.amplitude, 1.5)
+        self.assertEqual(count, int(np.sum(1.0 + 1.5 * message < 0)))
+        self.assertGreater(count, 0)
+        self.assertTrue(np.all(clamped * carrier >= 0.0))
+        
+        _, none_clamped = am_modulate_clamped(message, t, self.carrier_freq, self.amplitude, 0.5)
+        self.assertEqual(none_clamped, 0)
+        
+        self.assertTrue(is_overmodulated(SimulationParams(am_index=1.5)))
+        self.assertTrue(is_overmodulated(SimulationParams(am_index=0.8, message_amplitude=2.0)))
+        self.assertFalse(is_overmodulated(SimulationParams(am_index=1.0)))
+    
     def test_fm_modulation(self):
         """Test FM modulation."""
         t = generate_time_vector(self.sampling_rate, self.duration)
@@ -93,6 +150,295 @@ class TestSignalGeneration(unittest.TestCase):
         # The instantaneous frequency should vary around the carrier frequency
         # This is a basic check - more sophisticated tests would analyze the spectrum
     
+    def test_pm_modulation(self):
+        """Test PM modulation has a constant envelope and phase kp*m(t)."""
+        t = generate_time_vector(self.sampling_rate, self.duration)
+        message = message_signal(t, 100.0, self.amplitude)
+        
+        pm_signal = pm_modulate(message, t, self.carrier_freq, self.amplitude, 0.5)
+        expected = self.amplitude * np.sin(2 * np.pi * self.carrier_freq * t + 0.5 * message)
+        self.assertTrue(np.allclose(pm_signal, expected))
+        self.assertLessEqual(np.max(np.abs(pm_signal)), self.amplitude + 1e-12)
+    
+    def test_dsbsc_modulation(self):
+        """Test DSB-SC modulation has no carrier component."""
+        t = generate_time_vector(100000.0, self.duration)
+        message = message_signal(t, self.message_freq, self.amplitude)
+        
+        dsb_signal = dsbsc_modulate(message, t, 10000.0, self.amplitude)
+        
+        spectrum = np.abs(np.fft.rfft(dsb_signal))
+        freqs = np.fft.rfftfreq(len(dsb_signal), d=1.0/100000.0)
+        carrier_bin = np.argmin(np.abs(freqs - 10000.0))
+        sideband_bin = np.argmin(np.abs(freqs - 11000.0))
+        self.assertLess(spectrum[carrier_bin], 1e-6 * spectrum[sideband_bin])
+        
+        # Zero message means zero output (suppressed carrier)
+        self.assertTrue(np.allclose(dsbsc_modulate(np.zeros_like(t), t, 10000.0), 0.0))
+    
+    def test_fsk_modulation(self):
+        """Test binary FSK tone placement per bit."""
+        bits = np.array([0, 1, 1, 0])
+        fsk_signal = fsk_modulate(bits, 100000.0, 1000.0, 9000.0, 11000.0)
+        
+        self.assertEqual(len(fsk_signal), 400)
+        self.assertAlmostEqual(np.max(np.abs(fsk_signal)), 1.0, delta=0.01)
+        
+        for k, expected in enumerate([9000.0, 11000.0, 11000.0, 9000.0]):
+            symbol = fsk_signal[k * 100:(k + 1) * 100]
+            spectrum = np.abs(np.fft.rfft(symbol))
+            freqs = np.fft.rfftfreq(100, d=1.0/100000.0)
+            self.assertAlmostEqual(freqs[np.argmax(spectrum)], expected, places=3)
+        
+        with self.assertRaises(ValueError):
+            fsk_modulate(bits, 1000.0, 1000.0, 100.0, 200.0)
+    
+    def test_qam_constellation_gray_coded(self):
+        """Test unit energy and that nearest neighbours differ in exactly one bit."""
+        for order in (4, 16):
+            points = qam_constellation(order)
+            self.assertEqual(len(points), order)
+            self.assertAlmostEqual(np.mean(np.abs(points) ** 2), 1.0, places=12)
+            
+            spacing = np.min(np.abs(points[:, None] - points[None, :]) + np.eye(order) * 10)
+            for a in range(order):
+                for b in range(order):
+                    if a != b and np.isclose(abs(points[a] - points[b]), spacing):
+                        self.assertEqual(bin(a ^ b).count("1"), 1)
+        
+        with self.assertRaises(ValueError):
+            qam_constellation(8)
+    
+    def test_qam_bit_mapping_round_trip(self):
+        """Test bits -> symbols -> bits and the modulated waveform length."""
+        rng = np.random.default_rng(0)
+        for order in (4, 16):
+            k = order.bit_length() - 1
+            bits = rng.integers(0, 2, 40 * k)
+            symbols = qam_map_bits(bits, order)
+            self.assertTrue(np.array_equal(qam_demap_symbols(symbols, order), bits))
+            
+            qam_signal = qam_modulate(symbols, 10000.0, 500.0, 2500.0)
+            self.assertEqual(len(qam_signal), 40 * 20)
+        
+        with self.assertRaises(ValueError):
+            qam_map_bits(np.array([1, 0, 1]), 4)
+    
+    def test_trapezoidal_integration(self):
+        """Test trapezoidal integration of the message for FM."""
+        dt = 1.0 / self.sampling_rate
+        ramp = np.arange(11, dtype=float)
+        integral = integrate_message(ramp, dt, "trapezoidal")
+        # ∫ x dx from 0 to 10 (in samples) is exactly 50 for a linear ramp
+        self.assertAlmostEqual(integral[-1] / dt, 50.0, places=10)
+        self.assertEqual(integral[0], 0.0)
+        
+        t = generate_time_vector(self.sampling_rate, self.duration)
+        message = message_signal(t, 100.0, self.amplitude)
+        fm_rect = fm_modulate(message, t, self.carrier_freq, self.amplitude, 500.0, self.sampling_rate)
+        fm_trap = fm_modulate(message, t, self.carrier_freq, self.amplitude, 500.0, self.sampling_rate,
+                              integration="trapezoidal")
+        self.assertGreater(np.corrcoef(fm_rect, fm_trap)[0, 1], 0.99)
+        
+        with self.assertRaises(ValueError):
+            integrate_message(ramp, dt, "unknown")
+    
+    def test_simpson_integration_phase_error(self):
+        """Test Simpson integration accumulates less FM phase error than trapezoidal on a sine message."""
+        fs, freq, deviation = 20000.0, 1000.0, 5000.0
+        t = np.arange(20000) / fs
+        message = np.sin(2 * np.pi * freq * t)
+        exact = (1.0 - np.cos(2 * np.pi * freq * t)) / (2 * np.pi * freq)
+        
+        phase_errors = {}
+        for method in ("trapezoidal", "simpson"):
+            integral = integrate_message(message, 1.0 / fs, method)
+            phase_errors[method] = np.max(np.abs(fm_sensitivity(deviation) * (integral - exact)))
+        self.assertLess(phase_errors["simpson"], phase_errors["trapezoidal"] / 10)
+        self.assertLess(phase_errors["simpson"], 0.01)
+        
+        # Exact for a quadratic at both even and odd lengths
+        for n in (10, 11):
+            squares = np.arange(n, dtype=float) ** 2
+            self.assertAlmostEqual(integrate_message(squares, 1.0, "simpson")[-1], (n - 1) ** 3 / 3.0, places=9)
+        self.assertEqual(list(integrate_message(np.array([1.0, 1.0]), 1.0, "simpson")), [0.0, 1.0])
+    
+    def test_save_signals_csv(self):
+        """Test the wide CSV has a Time column plus one column per signal."""
+        t = generate_time_vector(self.sampling_rate, self.duration)
+        message = message_signal(t, self.message_freq, self.amplitude)
+        signals = {"message": message,
+                   "carrier": carrier_signal(t, self.carrier_freq, self.amplitude),
+                   "am": am_modulate(message, t, self.carrier_freq, self.amplitude, 0.5)}
+        
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "signals.csv")
+            save_signals_csv(t, signals, path)
+            with open(path) as f:
+                lines = f.read().splitlines()
+            self.assertEqual(lines[0], "Time,message,carrier,am")
+            self.assertEqual(len(lines), len(t) + 1)
+            
+            # Each column reads back as a single-signal file
+            t_loaded, m_loaded, _ = load_signal_csv(path)
+            self.assertTrue(np.allclose(t_loaded, t))
+            self.assertTrue(np.allclose(m_loaded, message))
+            
+            with self.assertRaises(ValueError):
+                save_signals_csv(t, {"message": message, "short": message[:-1]}, path)
+    
+    def test_multitone_signal(self):
+        """Test each tone shows up as a spectral peak with its relative amplitude."""
+        t = generate_time_vector(self.sampling_rate, 1.0)
+        freqs, amplitudes = [1000.0, 1500.0, 2300.0], [1.0, 0.5, 0.25]
+        x = multitone_signal(t, freqs, amplitudes)
+        
+        # 1 s of signal puts every tone exactly on a 1 Hz bin
+        magnitude = 2.0 * np.abs(np.fft.rfft(x)) / len(x)
+        peaks = np.sort(np.argsort(magnitude)[-3:])
+        self.assertEqual(list(peaks), [1000, 1500, 2300])
+        self.assertTrue(np.allclose(magnitude[peaks], amplitudes, atol=1e-6))
+        
+        with self.assertRaises(ValueError):
+            multitone_signal(t, freqs, amplitudes[:2])
+        with self.assertRaises(ValueError):
+            multitone_signal(t, [6000.0], [1.0])
+    
+    def test_signal_arithmetic(self):
+        """Test add/multiply/scale/mix on a constant signal and their length checks."""
+        twos = np.full(5, 2.0)
+        threes = np.full(5, 3.0)
+        self.assertTrue(np.array_equal(add_signals(twos, threes), np.full(5, 5.0)))
+        self.assertTrue(np.array_equal(multiply_signals(twos, threes), np.full(5, 6.0)))
+        self.assertTrue(np.array_equal(scale_signal(twos, 2.5), np.full(5, 5.0)))
+        self.assertTrue(np.array_equal(mix_signals([twos, threes], [1.0, 1.0]), np.full(5, 5.0)))
+        self.assertTrue(np.array_equal(mix_signals([twos, threes], [0.5, -1.0]), np.full(5, -2.0)))
+        
+        for op in (add_signals, multiply_signals):
+            with self.subTest(op=op.__name__):
+                with self.assertRaises(ValueError):
+                    op(twos, threes[:4])
+        with self.assertRaises(ValueError):
+            mix_signals([twos, threes[:4]], [1.0, 1.0])
+        with self.assertRaises(ValueError):
+            mix_signals([twos, threes], [1.0])
+        with self.assertRaises(ValueError):
+            mix_signals([], [])
+    
+    def test_normalize_signal(self):
+        """Test peak, RMS and z-score normalization."""
+        t = generate_time_vector(self.sampling_rate, self.duration)
+        x = 3.0 + 7.5 * message_signal(t, self.message_freq) + 0.5 * message_signal(t, 2300.0)
+        
+        self.assertEqual(np.max(np.abs(normalize_signal(x, "peak"))), 1.0)
+        self.assertAlmostEqual(np.sqrt(np.mean(normalize_signal(x, "rms") ** 2)), 1.0)
+        z = normalize_signal(x, "zscore")
+        self.assertAlmostEqual(np.mean(z), 0.0)
+        self.assertAlmostEqual(np.std(z), 1.0)
+        
+        self.assertTrue(np.array_equal(normalize_signal(np.zeros(4)), np.zeros(4)))
+        self.assertTrue(np.array_equal(normalize_signal(np.full(4, 2.0), "zscore"), np.zeros(4)))
+        with self.assertRaises(ValueError):
+            normalize_signal(x, "minmax")
+    
+    def test_chirp_signal(self):
+        """Test the chirp's instantaneous frequency is a linear ramp from start to end."""
+        from demod import instantaneous_frequency
+        
+        t = generate_time_vector(self.sampling_rate, 1.0)
+        chirp = chirp_signal(t, 100.0, 3000.0)
+        expected = 100.0 + 2900.0 * t / t[-1]
+        
+        # Skip the Hilbert transform's edge effects
+        middle = slice(len(t) // 20, -len(t) // 20)
+        measured = instantaneous_frequency(chirp, self.sampling_rate)
+        self.assertLess(np.max(np.abs(measured[middle] - expected[middle])), 20.0)
+        self.assertAlmostEqual(np.max(np.abs(chirp)), 1.0, places=3)
+    
+    def test_square_wave_harmonics(self):
+        """Test a 50% square wave has odd harmonics of 4A/(n pi) and no even ones."""
+        t = generate_time_vector(self.sampling_rate, 1.0)
+        square = square_wave_signal(t, 100.0, 2.0)
+        magnitude = 2.0 * np.abs(np.fft.rfft(square)) / len(square)
+        
+        for n in range(1, 8):
+            with self.subTest(harmonic=n):
+                if n % 2:
+                    self.assertAlmostEqual(magnitude[100 * n] / (4.0 * 2.0 / (n * np.pi)), 1.0, delta=0.02)
+                else:
+                    # Zero up to a sample or two landing on the other side of an edge
+                    self.assertLess(magnitude[100 * n], 0.01)
+        
+        self.assertAlmostEqual(np.mean(square_wave_signal(t, 100.0, 1.0, 0.25) > 0), 0.25, places=2)
+        with self.assertRaises(ValueError):
+            square_wave_signal(t, 100.0, 1.0, 1.0)
+        
+        ramp = arbitrary_signal(t, lambda x: 2.0 * x)
+        self.assertTrue(np.allclose(ramp, 2.0 * t))
+        self.assertTrue(np.all(arbitrary_signal(t, lambda x: 3.0) == 3.0))
+    
+    def test_load_signal_csv(self):
+        """Test loading a message signal from CSV and modulating it."""
+        t = generate_time_vector(self.sampling_rate, self.duration)
+        message = message_signal(t, self.message_freq, self.amplitude)
+        
+        with tempfile.NamedTemporaryFile(mode='w', suffix='.csv', delete=False) as f:
+            f.write("Time,Amplitude\n")
+            for ti, mi in zip(t, message):
+                f.write(f"{ti!r},{mi!r}\n")
+            temp_path = f.name
+        
+        try:
+            t_loaded, m_loaded, fs = load_signal_csv(temp_path, expected_duration=self.duration)
+            self.assertAlmostEqual(fs, self.sampling_rate, places=3)
+            self.assertTrue(np.allclose(m_loaded, message))
+            
+            am_signal = am_modulate(m_loaded, t_loaded, self.carrier_freq, self.amplitude, 0.5)
+            self.assertEqual(len(am_signal), len(message))
+            
+            with self.assertRaises(ValueError):
+                load_signal_csv(temp_path, expected_duration=2 * self.duration)
+        finally:
+            os.unlink(temp_path)
+    
+    def test_load_signal_csv_malformed(self):
+        """Test that malformed CSV rows raise a clear error."""
+        with tempfile.NamedTemporaryFile(mode='w', suffix='.csv', delete=False) as f:
+            f.write("Time,Amplitude\n0.0,0.1\n0.001,abc\n")
+            temp_path = f.name
+        
+        try:
+            with self.assertRaises(ValueError) as ctx:
+                load_signal_csv(temp_path)
+            self.assertIn(":3:", str(ctx.exception))
+        finally:
+            os.unlink(temp_path)
+    
+    def test_load_signal_wav(self):
+        """Test loading a 16-bit stereo WAV file."""
+        fs = 8000
+        n = 800
+        samples = (0.5 * np.sin(2 * np.pi * 440 * np.arange(n) / fs) * 32767).astype('<i2')
+        stereo = np.repeat(samples, 2)
+        
+        with tempfile.NamedTemporaryFile(suffix='.wav', delete=False) as f:
+            temp_path = f.name
+        
+        try:
+            with wave.open(temp_path, 'wb') as wav:
+                wav.setnchannels(2)
+                wav.setsampwidth(2)
+                wav.setframerate(fs)
+                wav.writeframes(stereo.tobytes())
+            
+            t, m, sampling_rate = load_signal_wav(temp_path, expected_duration=0.1)
+            self.assertEqual(sampling_rate, float(fs))
+            self.assertEqual(len(m), n)
+            self.assertEqual(len(t), n)
+            self.assertAlmostEqual(np.max(np.abs(m)), 0.5, delta=0.01)
+        finally:
+            os.unlink(temp_path)
+    
     def test_edge_cases(self):
         """Test edge cases."""
         # Very short duration
@@ -1,13 +1,27 @@
 """Unit tests for utility functions and performance analysis."""
 
+import dataclasses
 import unittest
 import numpy as np
 import tempfile
 import os
+import threading
+import time
 
 from config import SimulationParams
+import warnings
+
 from utils import calculate_output_snr, run_monte_carlo_trial, save_results_csv, save_results_json
-from utils import PerformanceResults
+from utils import calculate_ber, run_fsk_ber_simulation, snr_to_ebn0_db
+from utils import PerformanceResults, run_monte_carlo_simulation, calculate_thd
+from utils import calculate_output_snr_aligned, calculate_sinad, sinad_to_enob
+from utils import prepare_trial_signals, benchmark_trials, benchmark_carrier_generation, trial_seed, load_results_json
+from utils import confidence_interval_95, theoretical_am_output_snr_db, theoretical_fm_output_snr_db
+from utils import save_detailed_measurements_csv, calculate_processing_gain, summarize_processing_gain
+from utils import carson_bandwidth, estimate_delay, align_signals, load_results_csv, compare_to_baseline
+from utils import SimulationCancelled, TrialFailed, WelfordAccumulator, median_iqr
+from utils import reject_outliers, calculate_evm, run_capture_effect, calculate_snr_in_band
+from utils import sweep_modulation_index, snr_index_heatmap, calculate_papr, signal_stats, autocorrelation, cross_correlation, remove_dc_offset
 
 
 class TestUtilsFunctions(unittest.TestCase):
@@ -43,6 +57,83 @@ class TestUtilsFunctions(unittest.TestCase):
         self.assertGreater(snr_db, 0)
         self.assertLess(snr_db, 100)  # Should not be unreasonably high
     
+    def test_papr(self):
+        """Test constant-envelope FM sits near 3 dB PAPR while deep AM is higher."""
+        from signals import generate_time_vector, message_signal, am_modulate, fm_modulate
+        
+        t = generate_time_vector(100000.0, 0.1)
+        m = message_signal(t, 1000.0)
+        fm_papr = calculate_papr(fm_modulate(m, t, 10000.0, 1.0, 2000.0, 100000.0))
+        am_papr = calculate_papr(am_modulate(m, t, 10000.0, 1.0, 1.0))
+        self.assertAlmostEqual(fm_papr, 3.0, delta=0.1)
+        # (1 + ka)^2 / (1 + ka^2 / 2) peak-to-mean envelope power, plus the carrier's 3 dB
+        self.assertAlmostEqual(am_papr, 10 * np.log10(2 * 4 / 1.5), delta=0.2)
+        self.assertGreater(am_papr, fm_papr + 3.0)
+        self.assertTrue(np.isnan(calculate_papr(np.zeros(10))))
+        
+        self.params.snr_values = [10.0]
+        self.params.trials = 2
+        results = run_monte_carlo_simulation(self.params)
+        self.assertEqual(sorted(results.papr_db), ["am", "fm"])
+    
+    def test_signal_stats(self):
+        """Test a unit sine's summary: power 0.5, crest factor sqrt(2), energy = power * duration."""
+        fs = 10000.0
+        t = np.arange(20000) / fs
+        summary = signal_stats(np.sin(2 * np.pi * 50.0 * t), fs)
+        
+        self.assertAlmostEqual(summary.average_power, 0.5, places=6)
+        self.assertAlmostEqual(summary.rms, np.sqrt(0.5), places=6)
+        self.assertAlmostEqual(summary.peak, 1.0, places=6)
+        self.assertAlmostEqual(summary.crest_factor, np.sqrt(2), places=5)
+        self.assertAlmostEqual(summary.mean, 0.0, places=9)
+        self.assertAlmostEqual(summary.duration, 2.0)
+        self.assertAlmostEqual(summary.energy, 1.0, places=6)
+        
+        offset = signal_stats(np.full(100, -2.0), fs)
+        self.assertAlmostEqual(offset.mean, -2.0)
+        self.assertAlmostEqual(offset.crest_factor, 1.0)
+        self.assertTrue(np.isnan(signal_stats(np.zeros(10), fs).crest_factor))
+        
+        self.params.snr_values = [10.0]
+        self.params.trials = 2
+        results = run_monte_carlo_simulation(self.params)
+        self.assertEqual(sorted(results.signal_stats), ["am", "fm", "message"])
+        self.assertAlmostEqual(results.signal_stats["fm"].crest_factor, np.sqrt(2), delta=0.05)
+    
+    def test_snr_in_band(self):
+        """Test that band-limiting removes out-of-band noise from the SNR."""
+        fs = self.params.sampling_rate
+        t = np.arange(10000) / fs
+        original = np.sin(2 * np.pi * 100.0 * t)
+        noisy = original + np.random.default_rng(3).standard_normal(len(t))
+        
+        full_band = calculate_snr_in_band(original, noisy, 0.0, 0.49 * fs, fs)
+        in_band = calculate_snr_in_band(original, noisy, 0.0, 200.0, fs)
+        self.assertAlmostEqual(full_band, calculate_output_snr(original, noisy), delta=0.5)
+        # White noise: keeping 200 of 5000 Hz removes ~14 dB of noise
+        self.assertGreater(in_band, full_band + 10.0)
+        self.assertGreater(calculate_snr_in_band(original, noisy, 50.0, 200.0, fs), full_band + 10.0)
+        
+        with self.assertRaises(ValueError):
+            calculate_snr_in_band(original, noisy, 300.0, 200.0, fs)
+    
+    def test_dc_offset_removal(self):
+        """Test that the carrier level left in an AM envelope no longer counts as noise."""
+        from demod import am_demodulate_hilbert
+        from signals import am_modulate
+        
+        t = np.arange(10000) / 100000.0
+        message = np.sin(2 * np.pi * 1000.0 * t)
+        # Put back the carrier level the demodulator subtracts
+        envelope = 1.0 + am_demodulate_hilbert(am_modulate(message, t, 10000.0, 1.0, 0.5), t, 10000.0)
+        self.assertAlmostEqual(np.mean(remove_dc_offset(envelope)), 0.0)
+        
+        with_dc = calculate_output_snr(message, envelope, remove_dc=False)
+        without_dc = calculate_output_snr(message, envelope)
+        self.assertGreater(without_dc, with_dc + 6.0)
+        self.assertEqual(len(remove_dc_offset(np.array([]))), 0)
+    
     def test_output_snr_perfect_reconstruction(self):
         """Test output SNR with perfect reconstruction."""
         original = np.sin(2 * np.pi * 1000 * np.linspace(0, 0.1, 1000))
@@ -64,6 +155,80 @@ class TestUtilsFunctions(unittest.TestCase):
         self.assertIsInstance(snr_db, float)
         self.assertGreater(snr_db, 0)
     
+    def test_calculate_thd(self):
+        """Test THD on a pure sine, a clipped sine and a tone near Nyquist."""
+        fs = 10000.0
+        t = np.arange(1000) / fs
+        sine = np.sin(2 * np.pi * 1000.0 * t)
+        
+        self.assertLess(calculate_thd(sine, 1000.0, fs), 0.1)
+        
+        clipped = np.clip(sine, -0.5, 0.5)
+        self.assertGreater(calculate_thd(clipped, 1000.0, fs), 5.0)
+        
+        # Every harmonic of 3 kHz lies above the 5 kHz Nyquist, so nothing is counted
+        high = np.clip(np.sin(2 * np.pi * 3000.0 * t), -0.5, 0.5)
+        self.assertAlmostEqual(calculate_thd(high, 3000.0, fs), 0.0, places=6)
+        
+        with self.assertRaises(ValueError):
+            calculate_thd(sine, 6000.0, fs)
+    
+    def test_sinad_includes_distortion(self):
+        """Test that SINAD does not exceed SNR when the output is distorted."""
+        fs = 10000.0
+        t = np.arange(1000) / fs
+        original = np.sin(2 * np.pi * 500.0 * t)
+        rng = np.random.default_rng(0)
+        recovered = np.clip(original, -0.7, 0.7) + 0.001 * rng.standard_normal(len(t))
+        
+        snr = calculate_output_snr_aligned(original, recovered, fs, 500.0)
+        sinad = calculate_sinad(original, recovered, 500.0, fs)
+        self.assertLessEqual(sinad, snr)
+        
+        # Clean tone: noise dominates, so SINAD is high
+        self.assertGreater(calculate_sinad(original, original + 0.001 * rng.standard_normal(len(t)), 500.0, fs), 40.0)
+        self.assertAlmostEqual(sinad_to_enob(1.76 + 6.02 * 8), 8.0)
+    
+    def test_autocorrelation(self):
+        """Test a periodic signal's autocorrelation peaks at its period and white noise's does not."""
+        period = 40
+        x = np.sin(2 * np.pi * np.arange(2000) / period)
+        acf = autocorrelation(x, 2 * period)
+        self.assertEqual(len(acf), 2 * period + 1)
+        self.assertAlmostEqual(acf[0], 1.0)
+        self.assertEqual(period // 2 + int(np.argmax(acf[period // 2:3 * period // 2])), period)
+        self.assertAlmostEqual(acf[period // 2], -1.0, places=6)
+        
+        noise = np.random.default_rng(8).standard_normal(10000)
+        acf = autocorrelation(noise, 50)
+        self.assertAlmostEqual(acf[0], 1.0)
+        # 4 sigma of the sample autocorrelation is 4 / sqrt(N) = 0.04
+        self.assertLess(np.max(np.abs(acf[1:])), 0.04)
+        
+        xcf = cross_correlation(noise, np.roll(noise, 7), 20)
+        self.assertEqual(len(xcf), 41)
+        self.assertEqual(int(np.argmax(xcf)) - 20, 7)
+        self.assertTrue(np.all(np.abs(xcf) <= 1.0 + 1e-12))
+        with self.assertRaises(ValueError):
+            cross_correlation(noise[:10], noise[:10], 10)
+    
+    def test_estimate_delay_known_lags(self):
+        """Test that known integer delays are found and undone."""
+        rng = np.random.default_rng(5)
+        reference = rng.standard_normal(2000)
+        for delay in (0, 1, 7, 40, -5, -33):
+            with self.subTest(delay=delay):
+                delayed = np.roll(reference, delay)
+                self.assertEqual(estimate_delay(reference, delayed, 50), delay)
+                aligned = align_signals(reference, delayed, 50)
+                self.assertEqual(len(aligned), len(reference))
+                self.assertTrue(np.allclose(aligned[50:-50], reference[50:-50]))
+        
+        # Lags beyond max_lag cannot be reported
+        self.assertLessEqual(abs(estimate_delay(reference, np.roll(reference, 80), 50)), 50)
+        with self.assertRaises(ValueError):
+            estimate_delay(reference, reference, -1)
+    
     def test_monte_carlo_trial(self):
         """Test single Monte Carlo trial."""
         result = run_monte_carlo_trial(self.params, 10.0, 0)
@@ -81,6 +246,122 @@ class TestUtilsFunctions(unittest.TestCase):
         self.assertGreater(result.output_snr_fm_db, -10)
         self.assertLess(result.output_snr_am_db, 100)
         self.assertLess(result.output_snr_fm_db, 100)
+        
+        # Distortion of the demodulated tones is measured too
+        self.assertTrue(np.isfinite(result.am_thd_percent))
+        self.assertTrue(np.isfinite(result.fm_thd_percent))
+        self.assertGreaterEqual(result.am_thd_percent, 0.0)
+        self.assertTrue(np.isfinite(result.am_sinad_db))
+        self.assertTrue(np.isfinite(result.fm_sinad_db))
+    
+    def test_monte_carlo_trial_selectable_demodulators(self):
+        """Test Monte Carlo trial with the Hilbert AM and PLL FM demodulators."""
+        self.params.sampling_rate = 100000.0
+        self.params.carrier_freq = 10000.0
+        self.params.duration = 0.01
+        self.params.am_demodulator = "hilbert"
+        self.params.fm_demodulator = "pll"
+        
+        result = run_monte_carlo_trial(self.params, 20.0, 0)
+        
+        self.assertTrue(np.isfinite(result.output_snr_am_db))
+        self.assertTrue(np.isfinite(result.output_snr_fm_db))
+        self.assertGreater(result.output_snr_fm_db, 0)
+    
+    def test_processing_gain_fm_exceeds_am(self):
+        """Test that the PLL FM path shows more processing gain than AM."""
+        self.assertEqual(calculate_processing_gain(10.0, 25.0), 15.0)
+        
+        self.params.sampling_rate = 100000.0
+        self.params.carrier_freq = 10000.0
+        self.params.duration = 0.01
+        self.params.fm_demodulator = "pll"
+        self.params.snr_values = [20.0, 30.0]
+        self.params.trials = 3
+        
+        summaries = {s.modulation: s for s in summarize_processing_gain(run_monte_carlo_simulation(self.params))}
+        
+        self.assertEqual(set(summaries), {"AM", "FM"})
+        self.assertGreater(summaries["FM"].average_gain_db, summaries["AM"].average_gain_db)
+        self.assertGreaterEqual(summaries["FM"].peak_gain_db, summaries["FM"].average_gain_db)
+        self.assertIn(summaries["FM"].peak_input_snr_db, self.params.snr_values)
+    
+    def test_channel_filter_improves_fm_output_snr(self):
+        """Test that the receiver band-pass lifts FM out of threshold at low input SNR."""
+        self.params.sampling_rate = 100000.0
+        self.params.carrier_freq = 10000.0
+        self.params.duration = 0.02
+        
+        def mean_fm_snr(channel_filter):
+            self.params.channel_filter = channel_filter
+            return np.mean([run_monte_carlo_trial(self.params, 5.0, trial).output_snr_fm_db
+                            for trial in range(3)])
+        
+        # Carson bandwidth is 6 kHz of the 50 kHz noise band
+        self.assertGreater(mean_fm_snr(True), mean_fm_snr(False))
+        
+        self.params.channel_filter = True
+        self.assertTrue(np.isfinite(run_monte_carlo_trial(self.params, 10.0, 0).output_snr_am_db))
+    
+    def test_monte_carlo_trial_impulse_noise(self):
+        """Test that impulsive noise degrades the AM envelope path."""
+        clean = run_monte_carlo_trial(self.params, 20.0, 0)
+        
+        self.params.impulse_probability = 0.01
+        self.params.impulse_amplitude = 5.0
+        clicks = run_monte_carlo_trial(self.params, 20.0, 0)
+        
+        self.assertTrue(np.isfinite(clicks.output_snr_am_db))
+        self.assertTrue(np.isfinite(clicks.output_snr_fm_db))
+        self.assertLess(clicks.output_snr_am_db, clean.output_snr_am_db)
+    
+    def test_monte_carlo_trial_fading(self):
+        """Test Monte Carlo trial through a Rayleigh fading channel."""
+        self.params.fading_doppler_hz = 20.0
+        result = run_monte_carlo_trial(self.params, 20.0, 0)
+        
+        self.assertTrue(np.isfinite(result.output_snr_am_db))
+        self.assertTrue(np.isfinite(result.output_snr_fm_db))
+    
+    def test_monte_carlo_trial_dsbsc(self):
+        """Test that DSB-SC is only simulated when enabled."""
+        result = run_monte_carlo_trial(self.params, 10.0, 0)
+        self.assertTrue(np.isnan(result.output_snr_dsbsc_db))
+        
+        self.params.sampling_rate = 100000.0
+        self.params.carrier_freq = 10000.0
+        self.params.duration = 0.01
+        self.params.include_dsbsc = True
+        result = run_monte_carlo_trial(self.params, 20.0, 0)
+        self.assertTrue(np.isfinite(result.output_snr_dsbsc_db))
+        self.assertGreater(result.output_snr_dsbsc_db, 0)
+    
+    def test_fm_emphasis_improves_output_snr(self):
+        """Test that FM pre/de-emphasis raises output SNR at high input SNR."""
+        self.params.sampling_rate = 100000.0
+        self.params.duration = 0.05
+        self.params.carrier_freq = 20000.0
+        self.params.fm_deviation = 2000.0
+        
+        def mean_fm_snr(tau):
+            self.params.fm_emphasis_tau = tau
+            return np.mean([run_monte_carlo_trial(self.params, 30.0, trial).output_snr_fm_db
+                            for trial in range(3)])
+        
+        plain = mean_fm_snr(0.0)
+        # 318 µs puts the emphasis corner at 500 Hz, below the 1 kHz message
+        emphasized = mean_fm_snr(318e-6)
+        self.assertGreater(emphasized - plain, 3.0)
+    
+    def test_monte_carlo_trial_pm(self):
+        """Test that PM is simulated when enabled."""
+        self.params.sampling_rate = 100000.0
+        self.params.carrier_freq = 10000.0
+        self.params.duration = 0.01
+        self.params.include_pm = True
+        result = run_monte_carlo_trial(self.params, 20.0, 0)
+        self.assertTrue(np.isfinite(result.output_snr_pm_db))
+        self.assertGreater(result.output_snr_pm_db, 0)
     
     def test_monte_carlo_trial_reproducibility(self):
         """Test that Monte Carlo trials are reproducible with same parameters."""
@@ -91,6 +372,270 @@ class TestUtilsFunctions(unittest.TestCase):
         self.assertAlmostEqual(result1.output_snr_am_db, result2.output_snr_am_db, places=10)
         self.assertAlmostEqual(result1.output_snr_fm_db, result2.output_snr_fm_db, places=10)
     
+    def test_monte_carlo_trial_shared_signals(self):
+        """Test that reusing prepared clean signals gives identical results."""
+        self.params.include_dsbsc = True
+        self.params.include_pm = True
+        self.params.fm_emphasis_tau = 75e-6
+        signals = prepare_trial_signals(self.params)
+        am_before = signals.am_signal.copy()
+        
+        for trial in range(3):
+            fresh = run_monte_carlo_trial(self.params, 10.0, trial)
+            shared = run_monte_carlo_trial(self.params, 10.0, trial, signals)
+            self.assertEqual(fresh, shared)
+        
+        # Trials never write into the shared arrays
+        self.assertTrue(np.array_equal(signals.am_signal, am_before))
+    
+    def test_invalid_params_abort_simulation(self):
+        """Test that a bad configuration raises before any trial runs."""
+        self.params.sampling_rate = -1000.0
+        with self.assertRaises(ValueError) as ctx:
+            run_monte_carlo_simulation(self.params)
+        self.assertIn("sampling_rate", str(ctx.exception))
+        with self.assertRaises(ValueError):
+            run_fsk_ber_simulation(self.params)
+    
+    def test_worker_reuses_shared_signals(self):
+        """Test that pool workers get the clean signals once and tasks stay tiny."""
+        import pickle
+        import utils
+        
+        signals = prepare_trial_signals(self.params)
+        task = (10.0, 1, trial_seed(self.params.seed, 0, 1))
+        self.assertGreater(len(pickle.dumps(signals)), 100 * len(pickle.dumps(task)))
+        
+        saved = utils._worker_state
+        try:
+            utils._init_worker(self.params, signals)
+            result = utils._run_worker_task(task)
+        finally:
+            utils._worker_state = saved
+        expected = run_monte_carlo_trial(self.params, 10.0, 1, signals, task[2])
+        self.assertEqual(result.output_snr_am_db, expected.output_snr_am_db)
+        self.assertEqual(result.output_snr_fm_db, expected.output_snr_fm_db)
+    
+    def test_parallel_results_identical(self):
+        """Test that results do not depend on the number of workers."""
+        self.params.trials = 3
+        self.params.snr_min = 0.0
+        self.params.snr_max = 10.0
+        self.params.snr_step = 10.0
+        
+        runs = []
+        for workers in (1, 2, 8):
+            self.params.workers = workers
+            runs.append(run_monte_carlo_simulation(self.params))
+        
+        for run in runs[1:]:
+            for snr in runs[0].snr_levels:
+                self.assertEqual(run.am_results[snr], runs[0].am_results[snr])
+                self.assertEqual(run.fm_results[snr], runs[0].fm_results[snr])
+        
+        # Sub-seeds differ across SNR levels and trials but not across calls
+        self.assertEqual(trial_seed(0, 1, 2), trial_seed(0, 1, 2))
+        self.assertNotEqual(trial_seed(0, 1, 2), trial_seed(0, 2, 1))
+        self.assertNotEqual(trial_seed(0, 1, 2), trial_seed(1, 1, 2))
+    
+    def test_progress_callback(self):
+        """Test the progress callback counts every trial in order on both paths."""
+        self.params.trials = 3
+        self.params.snr_min = 0.0
+        self.params.snr_max = 10.0
+        self.params.snr_step = 10.0
+        
+        for workers in (1, 2):
+            with self.subTest(workers=workers):
+                self.params.workers = workers
+                calls = []
+                run_monte_carlo_simulation(self.params, progress=lambda *args: calls.append(args))
+                
+                self.assertEqual([c[0] for c in calls], list(range(1, 7)))
+                self.assertTrue(all(c[1] == 6 for c in calls))
+                elapsed = [c[2] for c in calls]
+                self.assertEqual(elapsed, sorted(elapsed))
+    
+    def test_cancel_simulation(self):
+        """Test that setting the cancel event stops the run early on both paths."""
+        self.params.trials = 200
+        
+        for workers in (1, 2):
+            with self.subTest(workers=workers):
+                self.params.workers = workers
+                cancel = threading.Event()
+                calls = []
+                
+                def progress(completed, total, elapsed):
+                    calls.append(completed)
+                    if completed == 2:
+                        cancel.set()
+                
+                start = time.perf_counter()
+                with self.assertRaises(SimulationCancelled):
+                    run_monte_carlo_simulation(self.params, progress=progress, cancel=cancel)
+                self.assertEqual(calls, [1, 2])
+                self.assertLess(time.perf_counter() - start, 30.0)
+    
+    def test_failing_trial_reports_error(self):
+        """Test that an exception inside a trial surfaces as TrialFailed instead of a hang."""
+        import modulation
+        
+        def broken(received, t, params):
+            raise RuntimeError("injected failure")
+        
+        modulation.register_modulation("broken", lambda m, t, p: m.copy(), broken)
+        self.addCleanup(modulation._REGISTRY.pop, "broken", None)
+        self.params.extra_modulations = ["broken"]
+        self.params.trials = 20
+        
+        for workers in (1, 2):
+            with self.subTest(workers=workers):
+                self.params.workers = workers
+                with self.assertRaises(TrialFailed) as ctx:
+                    run_monte_carlo_simulation(self.params)
+                self.assertEqual(ctx.exception.trial, 0)
+                self.assertIn("injected failure", str(ctx.exception))
+    
+    def test_concurrent_seeded_runs_reproducible(self):
+        """Test that seeded runs neither use nor disturb the global NumPy generator."""
+        from concurrent.futures import ThreadPoolExecutor
+        
+        def run(seed):
+            return run_monte_carlo_simulation(dataclasses.replace(self.params, seed=seed, trials=3))
+        
+        expected = {seed: run(seed) for seed in (1, 2)}
+        
+        np.random.seed(0)
+        before = np.random.random()
+        np.random.seed(0)
+        with ThreadPoolExecutor(max_workers=2) as executor:
+            concurrent = dict(zip((1, 2), executor.map(run, (1, 2))))
+        self.assertEqual(np.random.random(), before)
+        
+        for seed in (1, 2):
+            for snr in expected[seed].snr_levels:
+                self.assertEqual(concurrent[seed].am_results[snr], expected[seed].am_results[snr])
+                self.assertEqual(concurrent[seed].fm_results[snr], expected[seed].fm_results[snr])
+        self.assertNotEqual(expected[1].am_results, expected[2].am_results)
+    
+    def test_benchmark_trials(self):
+        """Test that the trial benchmark reports positive rates."""
+        fresh_rate, shared_rate = benchmark_trials(self.params, trials=2)
+        self.assertGreater(fresh_rate, 0.0)
+        self.assertGreater(shared_rate, 0.0)
+        direct_rate, recurrence_rate = benchmark_carrier_generation(self.params, repeats=2)
+        self.assertGreater(direct_rate, 0.0)
+        self.assertGreater(recurrence_rate, 0.0)
+    
+    def test_carson_bandwidth_matches_measured(self):
+        """Test Carson's rule against the measured 99% power bandwidth of the FM signal."""
+        from fft import occupied_bandwidth
+        
+        self.params.sampling_rate = 100000.0
+        self.params.carrier_freq = 20000.0
+        self.params.duration = 0.2
+        for deviation in (1000.0, 5000.0):
+            self.params.fm_deviation = deviation
+            carson = carson_bandwidth(self.params)
+            self.assertEqual(carson, 2.0 * (deviation + self.params.message_freq))
+            
+            fm_signal = prepare_trial_signals(self.params).fm_signal
+            measured = occupied_bandwidth(fm_signal, self.params.sampling_rate, 0.99, 4096)
+            self.assertGreater(measured / carson, 0.7)
+            self.assertLess(measured / carson, 1.5)
+        
+        # Deviation scales with the message amplitude
+        self.params.message_amplitude = 2.0
+        self.assertEqual(carson_bandwidth(self.params), 2.0 * (2.0 * 5000.0 + self.params.message_freq))
+    
+    def test_confidence_interval_95(self):
+        """Test that the 95% CI is symmetric about the mean and shrinks with more trials."""
+        rng = np.random.default_rng(0)
+        samples = rng.normal(10.0, 2.0, 1000)
+        
+        widths = []
+        for n in (5, 50, 500):
+            low, high = confidence_interval_95(samples[:n])
+            mean = np.mean(samples[:n])
+            self.assertAlmostEqual(mean - low, high - mean, places=10)
+            widths.append(high - low)
+        self.assertGreater(widths[0], widths[1])
+        self.assertGreater(widths[1], widths[2])
+        
+        # Large n approaches 1.96 * sigma / sqrt(n)
+        self.assertAlmostEqual(widths[2] / 2, 1.96 * np.std(samples[:500], ddof=1) / np.sqrt(500), delta=0.01)
+        self.assertEqual(confidence_interval_95([3.0]), (3.0, 3.0))
+    
+    def test_theoretical_output_snr(self):
+        """Test the textbook curves and that FM gains faster with modulation index."""
+        # Full AM modulation: mu^2/(2+mu^2) = 1/3
+        self.assertAlmostEqual(theoretical_am_output_snr_db(10.0, 1.0), 10.0 + 10 * np.log10(1 / 3))
+        # beta = 1: 3/2 gain
+        self.assertAlmostEqual(theoretical_fm_output_snr_db(10.0, 1.0), 10.0 + 10 * np.log10(1.5))
+        self.assertAlmostEqual(theoretical_fm_output_snr_db(10.0, 1.0, 10.0),
+                               theoretical_fm_output_snr_db(20.0, 1.0))
+        
+        indices = np.array([0.25, 0.5, 0.75, 1.0])
+        am_curve = theoretical_am_output_snr_db(10.0, indices)
+        fm_curve = theoretical_fm_output_snr_db(10.0, indices)
+        self.assertTrue(np.all(np.diff(am_curve) > 0))
+        self.assertTrue(np.all(np.diff(fm_curve) > np.diff(am_curve)))
+    
+    def test_calculate_evm(self):
+        """Test EVM is zero for identical symbols and tracks the added noise level."""
+        rng = np.random.default_rng(7)
+        reference = np.array([1 + 1j, -1 + 1j, -1 - 1j, 1 - 1j])[rng.integers(0, 4, 20000)] / np.sqrt(2)
+        self.assertEqual(calculate_evm(reference, reference), 0.0)
+        
+        # Complex noise of RMS sigma on unit-power symbols gives EVM = 100 * sigma %
+        for sigma in (0.05, 0.1, 0.2):
+            noise = sigma * (rng.standard_normal(len(reference)) + 1j * rng.standard_normal(len(reference))) / np.sqrt(2)
+            self.assertAlmostEqual(calculate_evm(reference + noise, reference), 100.0 * sigma, delta=2.0 * sigma)
+        
+        with self.assertRaises(ValueError):
+            calculate_evm(reference[:3], reference)
+        with self.assertRaises(ValueError):
+            calculate_evm([], [])
+    
+    def test_calculate_ber(self):
+        """Test BER counting and sync-error handling."""
+        sent = np.array([0, 1, 1, 0, 1, 0, 0, 1])
+        self.assertEqual(calculate_ber(sent, sent), 0.0)
+        self.assertEqual(calculate_ber(sent, 1 - sent), 1.0)
+        
+        received = sent.copy()
+        received[2] = 0
+        self.assertAlmostEqual(calculate_ber(sent, received), 1.0 / 8.0)
+        
+        with warnings.catch_warnings(record=True) as caught:
+            warnings.simplefilter("always")
+            ber = calculate_ber(sent, received[:4])
+        self.assertAlmostEqual(ber, 0.25)
+        self.assertTrue(any("sync" in str(w.message) for w in caught))
+    
+    def test_fsk_ber_simulation(self):
+        """Test that FSK BER is below 1e-3 at 15 dB SNR."""
+        self.params.sampling_rate = 100000.0
+        self.params.carrier_freq = 10000.0
+        self.params.snr_min = 15.0
+        self.params.snr_max = 15.0
+        self.params.trials = 10
+        
+        results = run_fsk_ber_simulation(self.params, bit_rate=1000.0, bits_per_trial=200)
+        
+        self.assertEqual(results.snr_levels, [15.0])
+        self.assertLess(results.ber[15.0], 1e-3)
+        self.assertAlmostEqual(results.ebn0_db[15.0], snr_to_ebn0_db(15.0, 100000.0, 1000.0))
+        self.assertAlmostEqual(snr_to_ebn0_db(0.0, 2000.0, 1000.0), 0.0)
+        
+        # Bits and noise both follow --seed
+        self.params.snr_min = self.params.snr_max = -5.0
+        first = run_fsk_ber_simulation(self.params, bit_rate=1000.0, bits_per_trial=200).ber[-5.0]
+        self.assertEqual(run_fsk_ber_simulation(self.params, bit_rate=1000.0, bits_per_trial=200).ber[-5.0], first)
+        self.params.seed += 1
+        self.assertNotEqual(run_fsk_ber_simulation(self.params, bit_rate=1000.0, bits_per_trial=200).ber[-5.0], first)
+    
     def test_save_results_csv(self):
         """Test saving results to CSV."""
         # Create mock results
@@ -118,9 +663,77 @@ class TestUtilsFunctions(unittest.TestCase):
                 self.assertIn('Input_SNR_dB', content)
                 self.assertIn('AM_Mean_Output_SNR_dB', content)
                 self.assertIn('FM_Mean_Output_SNR_dB', content)
+                self.assertNotIn('PM_Mean_Output_SNR_dB', content)
+            
+            # Opt-in schemes add their own columns
+            results.pm_means = {0.0: 1.0, 5.0: 2.0, 10.0: 3.0}
+            results.pm_stds = {0.0: 0.1, 5.0: 0.1, 10.0: 0.1}
+            save_results_csv(results, temp_path)
+            with open(temp_path, 'r') as f:
+                self.assertIn('PM_Mean_Output_SNR_dB', f.read())
         finally:
             os.unlink(temp_path)
     
+    def test_results_csv_round_trip(self):
+        """Test that load_results_csv restores what save_results_csv wrote."""
+        self.params.snr_values = [0.0, 10.0]
+        self.params.trials = 3
+        self.params.include_pm = True
+        results = run_monte_carlo_simulation(self.params)
+        
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "results.csv")
+            save_results_csv(results, path)
+            with open(path, 'a') as f:
+                f.write("\n")
+            loaded = load_results_csv(path)
+            
+            self.assertEqual(loaded.snr_levels, results.snr_levels)
+            for name in ('am_means', 'fm_means', 'am_stds', 'fm_stds', 'pm_means', 'pm_stds',
+                         'am_thd_means', 'fm_thd_means', 'am_sinad_means', 'fm_sinad_means',
+                         'am_medians', 'fm_medians', 'am_iqrs', 'fm_iqrs'):
+                original, restored = getattr(results, name), getattr(loaded, name)
+                self.assertEqual(sorted(restored), sorted(original), name)
+                for snr in original:
+                    self.assertAlmostEqual(restored[snr], original[snr], places=9, msg=name)
+            for snr in results.snr_levels:
+                self.assertTrue(np.allclose(loaded.am_ci95[snr], results.am_ci95[snr]))
+            self.assertEqual(loaded.dsbsc_means, {})
+            self.assertEqual(loaded.am_results, {})
+            
+            # Malformed numbers are reported with their line and column
+            with open(path) as f:
+                lines = f.read().splitlines()
+            fields = lines[2].split(',')
+            fields[1] = 'abc'
+            lines[2] = ','.join(fields)
+            with open(path, 'w') as f:
+                f.write('\n'.join(lines))
+            with self.assertRaises(ValueError) as ctx:
+                load_results_csv(path)
+            self.assertIn(':3:', str(ctx.exception))
+            self.assertIn('AM_Mean_Output_SNR_dB', str(ctx.exception))
+    
+    def test_compare_to_baseline(self):
+        """Test that identical results pass and a perturbed baseline is reported."""
+        self.params.snr_values = [0.0, 10.0]
+        self.params.trials = 3
+        results = run_monte_carlo_simulation(self.params)
+        
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "baseline.csv")
+            save_results_csv(results, path)
+            compare_to_baseline(results, path, 1e-9)
+            
+            results.fm_means[10.0] += 2.0
+            with self.assertRaises(ValueError) as ctx:
+                compare_to_baseline(results, path, 0.5)
+            message = str(ctx.exception)
+            self.assertIn("FM mean at 10 dB", message)
+            self.assertNotIn("AM mean", message)
+            self.assertNotIn("at 0 dB", message)
+            compare_to_baseline(results, path, 2.5)
+    
     def test_save_results_json(self):
         """Test saving results to JSON."""
         # Create mock results
@@ -151,6 +764,208 @@ class TestUtilsFunctions(unittest.TestCase):
         finally:
             os.unlink(temp_path)
     
+    def test_detailed_trials_capture(self):
+        """Test that save_detailed records one AM and one FM measurement per trial."""
+        self.params.snr_values = [0.0, 10.0]
+        self.assertEqual(run_monte_carlo_simulation(self.params).detailed_trials, [])
+        
+        self.params.save_detailed = True
+        serial = run_monte_carlo_simulation(self.params)
+        self.assertEqual(len(serial.detailed_trials), 40)
+        self.assertEqual(sum(m.modulation == "am" for m in serial.detailed_trials), 20)
+        am_at_10 = [m.output_snr_db for m in serial.detailed_trials if m.modulation == "am" and m.input_snr_db == 10.0]
+        self.assertEqual(am_at_10, serial.am_results[10.0])
+        
+        self.params.workers = 2
+        parallel = run_monte_carlo_simulation(self.params)
+        self.assertEqual(parallel.detailed_trials, serial.detailed_trials)
+        
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "trials.csv")
+            save_detailed_measurements_csv(serial, path)
+            with open(path) as f:
+                lines = f.read().splitlines()
+        self.assertEqual(lines[0], "Input_SNR_dB,Modulation,Trial,Output_SNR_dB")
+        self.assertEqual(len(lines), 41)
+    
+    def test_results_json_round_trip(self):
+        """Test that a simulation's JSON export reloads with config and stats."""
+        self.params.trials = 2
+        self.params.snr_values = [0.0, 10.0]
+        self.params.include_pm = True
+        results = run_monte_carlo_simulation(self.params)
+        
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "results.json")
+            save_results_json(results, path)
+            loaded = load_results_json(path)
+        
+        self.assertEqual(loaded.snr_levels, [0.0, 10.0])
+        self.assertEqual(loaded.params, self.params)
+        self.assertAlmostEqual(loaded.elapsed_seconds, results.elapsed_seconds)
+        for snr in results.snr_levels:
+            self.assertEqual(loaded.am_results[snr], results.am_results[snr])
+            self.assertAlmostEqual(loaded.fm_means[snr], results.fm_means[snr])
+            self.assertAlmostEqual(loaded.pm_means[snr], results.pm_means[snr])
+            self.assertAlmostEqual(loaded.am_sinad_means[snr], results.am_sinad_means[snr])
+            self.assertEqual(loaded.fm_ci95[snr], results.fm_ci95[snr])
+        self.assertEqual(loaded.signal_stats, results.signal_stats)
+    
+    def test_welford_accumulator(self):
+        """Test that Welford's update stays accurate where the sum-of-squares formula fails."""
+        offset = 1e9
+        deviations = np.random.default_rng(5).standard_normal(10000)
+        values = offset + deviations
+        # Exact reference: the offset does not change the variance
+        reference = float(np.var(deviations))
+        
+        acc = WelfordAccumulator()
+        for value in values:
+            acc.add(value)
+        naive = float(np.sum(values ** 2) / len(values) - np.mean(values) ** 2)
+        
+        welford_error = abs(acc.variance() - reference)
+        self.assertLess(welford_error, 1e-6)
+        self.assertLess(welford_error, abs(naive - reference))
+        self.assertAlmostEqual(acc.mean(), offset + np.mean(deviations), delta=1e-6)
+        self.assertAlmostEqual(acc.std_dev(ddof=1), float(np.std(deviations, ddof=1)), places=6)
+        self.assertTrue(np.isnan(WelfordAccumulator().mean()))
+    
+    def test_median_iqr_resists_outliers(self):
+        """Test that a few wild trials shift the mean but barely move the median or IQR."""
+        clean = list(np.linspace(9.0, 11.0, 50))
+        contaminated = clean[:-2] + [300.0, 500.0]
+        
+        clean_median, clean_iqr = median_iqr(clean)
+        median, iqr = median_iqr(contaminated)
+        self.assertAlmostEqual(clean_median, 10.0)
+        self.assertLess(abs(median - clean_median), 0.1)
+        self.assertLess(abs(iqr - clean_iqr), 0.1)
+        self.assertGreater(np.mean(contaminated) - np.mean(clean), 10.0)
+        self.assertTrue(np.isnan(median_iqr([])[0]))
+    
+    def test_outlier_rejection(self):
+        """Test that MAD rejection drops an injected outlier and the count is reported."""
+        values = list(10.0 + np.random.default_rng(6).standard_normal(50))
+        kept, rejected = reject_outliers(values + [1e6, float('inf')], "mad")
+        self.assertEqual(rejected, 2)
+        self.assertEqual(kept, values)
+        self.assertEqual(reject_outliers(values, "none"), (values, 0))
+        kept, rejected = reject_outliers(values, "percentile", 10.0)
+        self.assertEqual(len(kept) + rejected, 50)
+        self.assertGreater(rejected, 0)
+        
+        # Wired into the simulation: a scheme whose first trial per SNR point
+        # recovers the message perfectly, an absurd output SNR
+        import modulation
+        from signals import message_signal
+        calls = []
+        
+        def demodulate_with_outlier(received, t, params):
+            calls.append(None)
+            if len(calls) % 5 == 1:
+                return message_signal(t, params.message_freq, params.message_amplitude)
+            return received
+        
+        modulation.register_modulation("wild", lambda m, t, p: m.copy(), demodulate_with_outlier)
+        self.addCleanup(modulation._REGISTRY.pop, "wild", None)
+        self.params.extra_modulations = ["wild"]
+        self.params.snr_values = [0.0, 20.0]
+        self.params.trials = 5
+        self.params.outlier_policy = "mad"
+        results = run_monte_carlo_simulation(self.params)
+        
+        self.assertEqual(results.rejected_counts["wild"], {0.0: 1, 20.0: 1})
+        for snr in results.snr_levels:
+            self.assertEqual(len(results.extra_results["wild"][snr]), 4)
+            self.assertLess(abs(results.extra_means["wild"][snr]), 100.0)
+    
+    def test_snr_index_heatmap(self):
+        """Test the heatmap grid has one row per input SNR and rises along that axis."""
+        self.params.sampling_rate = 100000.0
+        self.params.carrier_freq = 10000.0
+        self.params.duration = 0.05
+        snr_values, indices = [0.0, 10.0, 20.0], [0.3, 0.6]
+        grid = snr_index_heatmap("am", self.params, snr_values, indices, 2)
+        self.assertEqual(grid.shape, (3, 2))
+        self.assertTrue(np.all(np.diff(grid, axis=0) > 0))
+        # The first input SNR shares its noise seeds with a 1D sweep at that SNR
+        sweep = sweep_modulation_index("am", self.params, indices, 0.0, 2)
+        self.assertTrue(np.allclose(grid[0], sweep.means))
+        with self.assertRaises(ValueError):
+            snr_index_heatmap("pm", self.params, snr_values, indices, 2)
+    
+    def test_convergence_stopping(self):
+        """Test that low-variance points stop after one batch while faded ones keep running."""
+        self.params.snr_values = [20.0]
+        self.params.trials = 5
+        self.params.convergence_tolerance = 0.5
+        self.params.max_trials = 30
+        steady = run_monte_carlo_simulation(self.params)
+        self.assertEqual(steady.trial_counts, {20.0: 5})
+        
+        # The first batch uses the same seeds as a fixed-count run
+        fixed = run_monte_carlo_simulation(dataclasses.replace(self.params, convergence_tolerance=0.0))
+        self.assertEqual(steady.am_results, fixed.am_results)
+        
+        self.params.fading_doppler_hz = 50.0
+        faded = run_monte_carlo_simulation(self.params)
+        self.assertGreater(faded.trial_counts[20.0], steady.trial_counts[20.0])
+        self.assertLessEqual(faded.trial_counts[20.0], 30)
+        self.assertEqual(len(faded.am_results[20.0]), faded.trial_counts[20.0])
+        
+        with tempfile.TemporaryDirectory() as tmp:
+            path = os.path.join(tmp, "results.csv")
+            save_results_csv(faded, path)
+            self.assertEqual(load_results_csv(path).trial_counts, faded.trial_counts)
+        
+        with self.assertRaises(ValueError):
+            run_monte_carlo_simulation(dataclasses.replace(self.params, max_trials=3))
+    
+    def test_modulation_index_sweep(self):
+        """Test that AM output SNR peaks below ka = 1 and falls off past the overmodulation cliff."""
+        self.params.sampling_rate = 100000.0
+        self.params.carrier_freq = 10000.0
+        self.params.duration = 0.05
+        indices = [0.3, 0.6, 0.9, 1.5, 2.0]
+        sweep = sweep_modulation_index("am", self.params, indices, 30.0, 3)
+        self.assertEqual(sweep.indices, indices)
+        self.assertEqual(len(sweep.means), len(indices))
+        self.assertLess(indices[int(np.argmax(sweep.means))], 1.0)
+        self.assertGreater(sweep.means[1], sweep.means[0])
+        self.assertLess(sweep.means[3], sweep.means[2])
+        self.assertEqual(self.params.am_index, 0.5)
+        
+        fm_sweep = sweep_modulation_index("fm", self.params, [1.0, 4.0], 30.0, 3)
+        self.assertGreater(fm_sweep.means[1], fm_sweep.means[0])
+        with self.assertRaises(ValueError):
+            sweep_modulation_index("pm", self.params, indices, 30.0, 3)
+        
+        # SNRs off the 0.1 dB grid are looked up by their rounded level
+        off_grid = sweep_modulation_index("am", self.params, [0.5], 12.25, 2)
+        self.assertEqual(off_grid.input_snr_db, 12.25)
+        self.assertTrue(np.isfinite(off_grid.means[0]))
+    
+    def test_fm_capture_effect(self):
+        """Test that FM follows the stronger of two co-channel signals while AM blends them."""
+        from noise import add_interferer
+        
+        x = np.sin(np.linspace(0.0, 20.0, 1000))
+        y = np.cos(np.linspace(0.0, 7.0, 1000))
+        mixed = add_interferer(x, y, -6.0)
+        self.assertAlmostEqual(np.mean((mixed - x) ** 2) / np.mean(x ** 2), 10 ** -0.6)
+        
+        self.params.sampling_rate = 100000.0
+        self.params.carrier_freq = 10000.0
+        results = run_capture_effect(self.params, [6.0, 10.0, 20.0])
+        for k, advantage in enumerate(results.advantage_db):
+            with self.subTest(advantage_db=advantage):
+                self.assertGreater(results.fm_wanted[k], results.fm_interferer[k])
+                self.assertGreater(results.fm_wanted[k], 0.8)
+        # At 10 dB the interferer leaks into AM in proportion, FM suppresses it
+        self.assertGreater(results.am_interferer[1], results.fm_interferer[1])
+        self.assertGreater(results.am_interferer[1], 0.2)
+    
     def test_edge_cases(self):
         """Test edge cases for utility functions."""
         # Test with very short signals
@@ -0,0 +1,52 @@
+"""Unit tests for the window functions."""
+
+import unittest
+import numpy as np
+
+from windows import WINDOW_TYPES, generate_window, apply_window, coherent_gain
+
+
+class TestWindows(unittest.TestCase):
+    """Test window generation and application."""
+
+    def test_symmetric_unit_peak(self):
+        """Test every window is symmetric and peaks at 1 in the middle."""
+        for window in WINDOW_TYPES:
+            with self.subTest(window=window):
+                w = generate_window(window, 65)
+                self.assertEqual(len(w), 65)
+                self.assertTrue(np.allclose(w, w[::-1]))
+                self.assertAlmostEqual(w[32], 1.0)
+                self.assertAlmostEqual(float(np.max(w)), 1.0)
+
+    def test_endpoints(self):
+        """Test the edge values of each window."""
+        expected = {"rectangular": 1.0, "hann": 0.0, "hamming": 0.08,
+                    "blackman": 0.0, "blackman-harris": 6e-5}
+        for window, value in expected.items():
+            with self.subTest(window=window):
+                w = generate_window(window, 64)
+                self.assertAlmostEqual(w[0], value, places=6)
+                self.assertAlmostEqual(w[-1], value, places=6)
+
+    def test_coherent_gain(self):
+        """Test the coherent gain equals the window's DC cosine term."""
+        expected = {"rectangular": 1.0, "hann": 0.5, "hamming": 0.54,
+                    "blackman": 0.42, "blackman-harris": 0.35875}
+        for window, gain in expected.items():
+            with self.subTest(window=window):
+                self.assertAlmostEqual(coherent_gain(generate_window(window, 4097)), gain, places=3)
+
+    def test_apply_window(self):
+        """Test windowing multiplies sample by sample and checks lengths."""
+        x = np.arange(8, dtype=float)
+        w = generate_window("hann", 8)
+        self.assertTrue(np.allclose(apply_window(x, w), x * w))
+        with self.assertRaises(ValueError):
+            apply_window(x, w[:4])
+        with self.assertRaises(ValueError):
+            generate_window("kaiser", 8)
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,55 @@
+from __future__ import annotations
+
+import numpy as np
+
+WINDOW_TYPES = ("rectangular", "hann", "hamming", "blackman", "blackman-harris")
+
+# Cosine-sum coefficients a_k of w[n] = sum_k (-1)^k a_k cos(2πkn / (N-1))
+_COSINE_TERMS = {
+    "rectangular": (1.0,),
+    "hann": (0.5, 0.5),
+    "hamming": (0.54, 0.46),
+    "blackman": (0.42, 0.5, 0.08),
+    "blackman-harris": (0.35875, 0.48829, 0.14128, 0.01168),
+}
+
+
+def generate_window(window: str, length: int) -> np.ndarray:
+    """
+    Symmetric window of the given length, in its standard unnormalized form.
+
+    Every type is a cosine sum evaluated over n / (length - 1) whose
+    coefficients add up to 1, so the window is symmetric and (for odd
+    lengths) peaks at exactly 1 at its center. Even lengths straddle the
+    center and peak just below 1; no rescaling is applied.
+
+    Args:
+        window: One of WINDOW_TYPES
+        length: Number of samples
+
+    Returns:
+        Window coefficients
+    """
+    if window not in _COSINE_TERMS:
+        raise ValueError(f"Unknown window type: {window}")
+    if length < 1:
+        raise ValueError("Window length must be positive")
+    phase = 2.0 * np.pi * np.arange(length) / max(length - 1, 1)
+    w = np.zeros(length)
+    for k, a in enumerate(_COSINE_TERMS[window]):
+        w += (-1) ** k * a * np.cos(k * phase)
+    return w
+
+
+def apply_window(x: np.ndarray, window: np.ndarray) -> np.ndarray:
+    """Multiply x sample by sample with a window of the same length."""
+    x = np.asarray(x, dtype=float)
+    window = np.asarray(window, dtype=float)
+    if len(x) != len(window):
+        raise ValueError("Signal and window must have the same length")
+    return x * window
+
+
+def coherent_gain(window: np.ndarray) -> float:
+    """Mean of the window: the amplitude scaling it applies to a bin-centered tone."""
+    return float(np.mean(window))